@@ -13,6 +13,9 @@ const (
 	ContentTypeAlert            ContentType = 21
 	ContentTypeHandshake        ContentType = 22
 	ContentTypeApplicationData  ContentType = 23
+	ContentTypeHeartbeat        ContentType = 24
+	ContentTypeTLS12CID         ContentType = 25
+	ContentTypeACK              ContentType = 26
 )
 
 // getDesc resturns description of a content type
@@ -26,6 +29,12 @@ func (c ContentType) getDesc() string {
 		return "handshake"
 	case ContentTypeApplicationData:
 		return "application_data"
+	case ContentTypeHeartbeat:
+		return "heartbeat"
+	case ContentTypeTLS12CID:
+		return "tls12_cid"
+	case ContentTypeACK:
+		return "ack"
 	default:
 		return "unknown"
 	}
@@ -36,10 +45,16 @@ func (c ContentType) String() string {
 	return fmt.Sprintf("%s(%d)", c.getDesc(), c)
 }
 
-// IsValid method checks if it's a valid value
+// IsValid method checks if it's a valid value in tls records, tls12_cid and
+// ack are only valid in dtls records
 func (c ContentType) IsValid() bool {
-	if c >= 20 && c <= 23 {
+	if c >= 20 && c <= 24 {
 		return true
 	}
 	return false
 }
+
+// IsValidDTLS method checks if it's a valid value in dtls records
+func (c ContentType) IsValidDTLS() bool {
+	return c.IsValid() || c == ContentTypeTLS12CID || c == ContentTypeACK
+}
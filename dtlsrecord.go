@@ -0,0 +1,291 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlslayer
+
+import (
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Header lengths of dtls records
+const (
+	// DTLSRecordHeaderLen is the length of the header of dtls 1.0-1.2 records without connection id
+	DTLSRecordHeaderLen = 13
+	// dtlsUnifiedMask and dtlsUnifiedBits identify the first byte of a dtls 1.3 unified header
+	dtlsUnifiedMask = 0xe0
+	dtlsUnifiedBits = 0x20
+)
+
+// Flags of the first byte of dtls 1.3 unified header (rfc9147)
+const (
+	dtlsUnifiedCID    = 0x10
+	dtlsUnifiedSeq16  = 0x08
+	dtlsUnifiedLength = 0x04
+	dtlsUnifiedEpoch  = 0x03
+)
+
+// DTLSRecord is the struct for DTLS records. It supports the header of
+// DTLS 1.0-1.2, the header with connection id of tls12_cid records (rfc9146)
+// and the unified header of DTLS 1.3 protected records (rfc9147).
+type DTLSRecord struct {
+	layers.BaseLayer
+
+	Type    ContentType     `json:"type"`
+	Version ProtocolVersion `json:"version"`
+	Epoch   uint16          `json:"epoch"`
+	// SequenceNumber has 48 bits, in unified headers it only has the low 8 or
+	// 16 bits and it's encrypted
+	SequenceNumber uint64 `json:"sequenceNumber"`
+	ConnectionID   []byte `json:"connectionID,omitempty"`
+	Len            uint16 `json:"len"`
+
+	// Unified is true if record has a dtls 1.3 unified header, its content
+	// type is protected so Type is application data, Version is dtls 1.3 and
+	// Epoch has only the low 2 bits
+	Unified bool `json:"unified"`
+
+	// CIDLength is the length of connection ids, it has to be known because
+	// records don't include it
+	CIDLength int `json:"-"`
+}
+
+func (d *DTLSRecord) String() string {
+	if d.Unified {
+		return fmt.Sprintf("%s unified epoch=%d seq=%d (len=%d)", d.Version, d.Epoch, d.SequenceNumber, d.Len)
+	}
+	return fmt.Sprintf("%s %s epoch=%d seq=%d (len=%d)", d.Version, d.Type, d.Epoch, d.SequenceNumber, d.Len)
+}
+
+// LayerTypeDTLSRecord is the registered layer in gopacket
+var LayerTypeDTLSRecord = gopacket.RegisterLayerType(
+	1449,
+	gopacket.LayerTypeMetadata{
+		Name:    "DTLSRecord",
+		Decoder: DTLSRecordDecoder{},
+	},
+)
+
+// dtlsContentTypeLayers stores the layer types that decode the payload of dtls records
+var dtlsContentTypeLayers [256]gopacket.LayerType
+
+// RegisterDTLSContentTypeLayerType sets the layer type returned by NextLayerType
+// for dtls records of the content type. It's used by packages that decode dtls messages.
+func RegisterDTLSContentTypeLayerType(ctype ContentType, ltype gopacket.LayerType) {
+	dtlsContentTypeLayers[ctype] = ltype
+}
+
+// CanDecode satisfaces the interface
+func (d *DTLSRecord) CanDecode() gopacket.LayerClass {
+	return LayerTypeDTLSRecord
+}
+
+// LayerType satisfaces the interface
+func (d *DTLSRecord) LayerType() gopacket.LayerType {
+	return LayerTypeDTLSRecord
+}
+
+// NextLayerType satisfaces the interface, it returns the layer type registered
+// for the content type of the record or payload if there isn't any
+func (d *DTLSRecord) NextLayerType() gopacket.LayerType {
+	if ltype := dtlsContentTypeLayers[d.Type]; ltype != gopacket.LayerTypeZero {
+		return ltype
+	}
+	return gopacket.LayerTypePayload
+}
+
+// Payload satisfaces the interface and returns the payload of the record
+func (d *DTLSRecord) Payload() []byte {
+	return d.BaseLayer.Payload
+}
+
+// DTLSRecordDecoder is a gopacket decoder of dtls records. The length of
+// connection ids is negotiated in the handshake and it isn't included in the
+// records, so flows using connection ids need a decoder with CIDLength set,
+// e.g. gopacket.NewPacket(data, DTLSRecordDecoder{CIDLength: 8}, opts).
+// The decoder registered in LayerTypeDTLSRecord doesn't use connection ids.
+type DTLSRecordDecoder struct {
+	CIDLength int
+}
+
+// Decode decodes all the records of the datagram, each record layer is
+// followed by the layer of its messages if there is one registered. The first
+// record is the application layer.
+func (dec DTLSRecordDecoder) Decode(data []byte, p gopacket.PacketBuilder) error {
+	for first := true; len(data) > 0; first = false {
+		d := &DTLSRecord{CIDLength: dec.CIDLength}
+		err := d.DecodeFromBytes(data, p)
+		if err != nil {
+			return err
+		}
+		p.AddLayer(d)
+		if first {
+			p.SetApplicationLayer(d)
+		}
+		if next := d.NextLayerType(); next != gopacket.LayerTypePayload {
+			if err := next.Decode(d.Payload(), p); err != nil {
+				return err
+			}
+		}
+		data = data[len(d.BaseLayer.Contents)+len(d.BaseLayer.Payload):]
+	}
+
+	return nil
+}
+
+// IsDTLSDatagram returns true if the first byte of data is in the range of
+// dtls of rfc7983. WebRTC multiplexes dtls with stun and srtp on ports
+// negotiated by signaling, so its datagrams can't be registered by port and
+// they have to be decoded with DecodeDTLSRecords or a DTLSRecordDecoder.
+func IsDTLSDatagram(data []byte) bool {
+	return len(data) > 0 && data[0] >= 20 && data[0] <= 63
+}
+
+// IsDTLSUnifiedHeader returns true if the first byte of data is a dtls 1.3 unified header
+func IsDTLSUnifiedHeader(data []byte) bool {
+	return len(data) > 0 && data[0]&dtlsUnifiedMask == dtlsUnifiedBits
+}
+
+// HasDTLSHeader returns true if byte slice has a valid dtls record header
+func HasDTLSHeader(data []byte) bool {
+	d := DTLSRecord{}
+	err := d.decodeHeader(data)
+	return err == nil
+}
+
+// DecodeFromBytes load the contents of a dtls record from a byte slice. A
+// datagram may have several records, the rest are after Contents and Payload.
+func (d *DTLSRecord) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	err := d.decodeHeader(data)
+	if err != nil {
+		return err
+	}
+	hlen := len(d.BaseLayer.Contents)
+	// checks if completed payload
+	if len(data)-hlen < int(d.Len) {
+		d.BaseLayer.Payload = data[hlen:]
+		if df != nil {
+			df.SetTruncated()
+		}
+		return ErrTLSWrongPayload
+	}
+	d.BaseLayer.Payload = data[hlen : hlen+int(d.Len)]
+	return nil
+}
+
+// decodeHeader decodes the header and sets it as contents of the layer
+func (d *DTLSRecord) decodeHeader(data []byte) error {
+	d.Unified = IsDTLSUnifiedHeader(data)
+	if d.Unified {
+		return d.decodeUnifiedHeader(data)
+	}
+	if len(data) < DTLSRecordHeaderLen {
+		return ErrTLSWrongSize
+	}
+	ctype := ContentType(data[0])
+	if !ctype.IsValidDTLS() {
+		return ErrTLSWrongContentType
+	}
+	pversion := ProtocolVersion(uint16(data[1])<<8 | uint16(data[2]))
+	if !pversion.IsDTLS() {
+		return ErrTLSWrongProtocolVersion
+	}
+	d.Type = ctype
+	d.Version = pversion
+	d.Epoch = uint16(data[3])<<8 | uint16(data[4])
+	d.SequenceNumber = uint64(data[5])<<40 | uint64(data[6])<<32 | uint64(data[7])<<24 |
+		uint64(data[8])<<16 | uint64(data[9])<<8 | uint64(data[10])
+	d.ConnectionID = nil
+
+	off := 11
+	if ctype == ContentTypeTLS12CID {
+		if d.CIDLength <= 0 {
+			return ErrDTLSConnectionID
+		}
+		if len(data) < off+d.CIDLength+2 {
+			return ErrTLSWrongSize
+		}
+		d.ConnectionID = data[off : off+d.CIDLength]
+		off += d.CIDLength
+	}
+	d.Len = uint16(data[off])<<8 | uint16(data[off+1])
+	if d.Len > MaxTLSRecordSize {
+		return ErrTLSWrongSize
+	}
+	d.BaseLayer.Contents = data[:off+2]
+	return nil
+}
+
+// decodeUnifiedHeader decodes a dtls 1.3 unified header
+func (d *DTLSRecord) decodeUnifiedHeader(data []byte) error {
+	flags := data[0]
+	d.Type = ContentTypeApplicationData
+	d.Version = VersionDTLS13
+	d.Epoch = uint16(flags & dtlsUnifiedEpoch)
+	d.ConnectionID = nil
+
+	off := 1
+	if flags&dtlsUnifiedCID != 0 {
+		if d.CIDLength <= 0 {
+			return ErrDTLSConnectionID
+		}
+		if len(data) < off+d.CIDLength {
+			return ErrTLSWrongSize
+		}
+		d.ConnectionID = data[off : off+d.CIDLength]
+		off += d.CIDLength
+	}
+	if flags&dtlsUnifiedSeq16 != 0 {
+		if len(data) < off+2 {
+			return ErrTLSWrongSize
+		}
+		d.SequenceNumber = uint64(data[off])<<8 | uint64(data[off+1])
+		off += 2
+	} else {
+		if len(data) < off+1 {
+			return ErrTLSWrongSize
+		}
+		d.SequenceNumber = uint64(data[off])
+		off++
+	}
+	if flags&dtlsUnifiedLength != 0 {
+		if len(data) < off+2 {
+			return ErrTLSWrongSize
+		}
+		d.Len = uint16(data[off])<<8 | uint16(data[off+1])
+		off += 2
+	} else {
+		// record extends to the end of the datagram
+		if len(data)-off > int(MaxTLSRecordSize) {
+			return ErrTLSWrongSize
+		}
+		d.Len = uint16(len(data) - off)
+	}
+	if d.Len > MaxTLSRecordSize {
+		return ErrTLSWrongSize
+	}
+	d.BaseLayer.Contents = data[:off]
+	return nil
+}
+
+// DecodeDTLSRecords decodes all the records of a datagram using the length of
+// connection ids. Records decoded before an error are returned with it.
+func DecodeDTLSRecords(data []byte, cidLength int) ([]*DTLSRecord, error) {
+	var records []*DTLSRecord
+	for len(data) > 0 {
+		d := &DTLSRecord{CIDLength: cidLength}
+		if err := d.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+			return records, err
+		}
+		records = append(records, d)
+		data = data[len(d.BaseLayer.Contents)+len(d.BaseLayer.Payload):]
+	}
+	return records, nil
+}
+
+func init() {
+	// CoAP and syslog over dtls
+	layers.RegisterUDPPortLayerType(5684, LayerTypeDTLSRecord)
+	layers.RegisterUDPPortLayerType(6514, LayerTypeDTLSRecord)
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlslayer
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// dtls 1.2 alert and change_cipher_spec records in a datagram
+var testDatagramDTLS1 = []byte{
+	0x15, 0xfe, 0xfd, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x02, 0x01, 0x00,
+	0x14, 0xfe, 0xfd, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x00, 0x01, 0x01,
+}
+
+// tls12_cid record with a connection id of 4 bytes
+var testRecordDTLSCID1 = []byte{
+	0x19, 0xfe, 0xfd, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, 0xca, 0xfe, 0xba, 0xbe, 0x00, 0x03, 0xaa, 0xbb, 0xcc,
+}
+
+func TestDecodeDTLSRecord(t *testing.T) {
+	records, err := DecodeDTLSRecords(testDatagramDTLS1, 0)
+	if err != nil {
+		t.Fatal("decoding dtls records:", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected records: 2, got: %v", len(records))
+	}
+	r := records[0]
+	if r.Type != ContentTypeAlert || r.Version != VersionDTLS12 || r.Epoch != 0 || r.SequenceNumber != 5 || r.Len != 2 {
+		t.Errorf("unexpected record: %v", r)
+	}
+	if !bytes.Equal(r.Payload(), []byte{0x01, 0x00}) {
+		t.Errorf("unexpected payload: %x", r.Payload())
+	}
+	r = records[1]
+	if r.Type != ContentTypeChangeCipherSpec || r.Epoch != 1 || r.SequenceNumber != 0x0102 || r.Unified {
+		t.Errorf("unexpected record: %v", r)
+	}
+
+	if HasDTLSHeader(testRecordCCS) {
+		t.Error("unexpected dtls header in tls record")
+	}
+	if _, err := DecodeDTLSRecords(testDatagramDTLS1[:20], 0); err != ErrTLSWrongSize {
+		t.Errorf("expected error: %v, got: %v", ErrTLSWrongSize, err)
+	}
+}
+
+func TestDecodeDTLSRecordCID(t *testing.T) {
+	d := &DTLSRecord{}
+	if err := d.DecodeFromBytes(testRecordDTLSCID1, gopacket.NilDecodeFeedback); err != ErrDTLSConnectionID {
+		t.Errorf("expected error: %v, got: %v", ErrDTLSConnectionID, err)
+	}
+	d.CIDLength = 4
+	if err := d.DecodeFromBytes(testRecordDTLSCID1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("decoding dtls record:", err)
+	}
+	if d.Type != ContentTypeTLS12CID || !bytes.Equal(d.ConnectionID, []byte{0xca, 0xfe, 0xba, 0xbe}) || d.Len != 3 {
+		t.Errorf("unexpected record: %v cid=%x", d, d.ConnectionID)
+	}
+	if d.NextLayerType() != gopacket.LayerTypePayload {
+		t.Errorf("expected next layer: %v, got: %v", gopacket.LayerTypePayload, d.NextLayerType())
+	}
+}
+
+func TestDecodeDTLSUnifiedHeader(t *testing.T) {
+	tests := []struct {
+		data    []byte
+		cidLen  int
+		cid     []byte
+		epoch   uint16
+		seq     uint64
+		length  uint16
+		payload int
+	}{
+		// sequence of 8 bits without length
+		{[]byte{0x23, 0x05, 0xaa, 0xbb, 0xcc}, 0, nil, 3, 5, 3, 3},
+		// sequence of 16 bits with length
+		{[]byte{0x2e, 0x01, 0x02, 0x00, 0x02, 0xaa, 0xbb}, 0, nil, 2, 0x0102, 2, 2},
+		// connection id, sequence of 16 bits and length
+		{[]byte{0x3d, 0x0a, 0x0b, 0x01, 0x02, 0x00, 0x01, 0xaa}, 2, []byte{0x0a, 0x0b}, 1, 0x0102, 1, 1},
+	}
+	for i, test := range tests {
+		d := &DTLSRecord{CIDLength: test.cidLen}
+		if err := d.DecodeFromBytes(test.data, gopacket.NilDecodeFeedback); err != nil {
+			t.Errorf("test %d: decoding dtls record: %v", i, err)
+			continue
+		}
+		if !d.Unified || d.Type != ContentTypeApplicationData || d.Version != VersionDTLS13 {
+			t.Errorf("test %d: expected unified header: %v", i, d)
+		}
+		if d.Epoch != test.epoch || d.SequenceNumber != test.seq || d.Len != test.length || len(d.Payload()) != test.payload {
+			t.Errorf("test %d: unexpected record: %v", i, d)
+		}
+		if !bytes.Equal(d.ConnectionID, test.cid) {
+			t.Errorf("test %d: expected cid: %x, got: %x", i, test.cid, d.ConnectionID)
+		}
+	}
+	d := &DTLSRecord{}
+	if err := d.DecodeFromBytes([]byte{0x3d, 0x0a, 0x0b}, gopacket.NilDecodeFeedback); err != ErrDTLSConnectionID {
+		t.Errorf("expected error: %v, got: %v", ErrDTLSConnectionID, err)
+	}
+}
+
+func TestDecodeDTLSPacket(t *testing.T) {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x01, 0x02, 0x03, 0x04, 0x05},
+		DstMAC:       net.HardwareAddr{0x00, 0x01, 0x02, 0x03, 0x04, 0x06},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 5684}
+	udp.SetNetworkLayerForChecksum(ip)
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	err := gopacket.SerializeLayers(buf, opts, eth, ip, udp, gopacket.Payload(testDatagramDTLS1))
+	if err != nil {
+		t.Fatal("serializing packet:", err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LinkTypeEthernet, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	checkLayers(p, []gopacket.LayerType{layers.LayerTypeEthernet, layers.LayerTypeIPv4, layers.LayerTypeUDP, LayerTypeDTLSRecord, LayerTypeDTLSRecord}, t)
+	d, ok := p.ApplicationLayer().(*DTLSRecord)
+	if !ok {
+		t.Fatal("No DTLSRecord layer type found in packet")
+	}
+	if d.Type != ContentTypeAlert || d.SequenceNumber != 5 {
+		t.Errorf("unexpected record: %v", d)
+	}
+	if d = p.Layers()[4].(*DTLSRecord); d.Type != ContentTypeChangeCipherSpec || d.Epoch != 1 {
+		t.Errorf("unexpected second record: %v", d)
+	}
+	if !IsDTLSDatagram(testDatagramDTLS1) || IsDTLSDatagram([]byte{0x00, 0x01}) || IsDTLSDatagram([]byte{0x80}) {
+		t.Error("unexpected rfc7983 demultiplexing")
+	}
+
+	p = gopacket.NewPacket(testRecordDTLSCID1, DTLSRecordDecoder{CIDLength: 4}, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	d, ok = p.ApplicationLayer().(*DTLSRecord)
+	if !ok || !bytes.Equal(d.ConnectionID, []byte{0xca, 0xfe, 0xba, 0xbe}) {
+		t.Errorf("unexpected record with connection id: %v", p.ApplicationLayer())
+	}
+}
+
+func TestDTLSVersions(t *testing.T) {
+	tests := []struct {
+		version ProtocolVersion
+		desc    string
+		dtls    bool
+	}{
+		{VersionDTLS10, "DTLS_1.0", true},
+		{VersionDTLS12, "DTLS_1.2", true},
+		{VersionDTLS13, "DTLS_1.3", true},
+		{VersionTLS12, "TLS_1.2", false},
+	}
+	for _, test := range tests {
+		if test.version.IsDTLS() != test.dtls {
+			t.Errorf("%v: expected dtls: %v", test.version, test.dtls)
+		}
+		if want := fmt.Sprintf("%s(%d)", test.desc, test.version); test.version.String() != want {
+			t.Errorf("expected string: %v, got: %v", want, test.version.String())
+		}
+	}
+	if !VersionDTLS12.Less(VersionDTLS13) || VersionDTLS13.Less(VersionTLS12) || !VersionTLS12.Less(VersionDTLS13) {
+		t.Error("unexpected order of dtls versions")
+	}
+	if VersionDTLS10.TLSVersion() != VersionTLS11 || VersionDTLS13.TLSVersion() != VersionTLS13 || VersionTLS12.TLSVersion() != VersionTLS12 {
+		t.Error("unexpected tls versions of dtls versions")
+	}
+	if VersionDTLS12.IsValid() {
+		t.Error("dtls version is valid tls version")
+	}
+}
@@ -13,4 +13,5 @@ var (
 	ErrTLSWrongSize            = errors.New("tls record is of wrong size")
 	ErrTLSWrongPayload         = errors.New("tls record payload size doesn't match with record size")
 	ErrTLSPayloadEmpty         = errors.New("tls record payload is empty")
+	ErrDTLSConnectionID        = errors.New("dtls record has a connection id of unknown length")
 )
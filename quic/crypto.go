@@ -0,0 +1,155 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// Lengths of the protection of initial packets, they use AEAD_AES_128_GCM
+const (
+	initialKeyLen = 16
+	initialIVLen  = 12
+	sampleLen     = 16
+)
+
+// Keys are the keys that protect initial packets of one endpoint (rfc9001)
+type Keys struct {
+	Key []byte `json:"key"`
+	IV  []byte `json:"iv"`
+	HP  []byte `json:"hp"`
+
+	aead cipher.AEAD
+	hp   cipher.Block
+}
+
+// NewInitialKeys derives the keys of initial packets from the destination
+// connection id of the first initial packet sent by client. They are the keys
+// of the client or the server if server is true.
+func NewInitialKeys(v Version, dcid []byte, server bool) (*Keys, error) {
+	r, ok := versionReg[v]
+	if !ok {
+		return nil, ErrQUICUnsupportedVersion
+	}
+	initialSecret := hkdfExtract(r.salt, dcid)
+	label := "client in"
+	if server {
+		label = "server in"
+	}
+	secret := hkdfExpandLabel(initialSecret, label, sha256.Size)
+	k := &Keys{
+		Key: hkdfExpandLabel(secret, r.prefix+"key", initialKeyLen),
+		IV:  hkdfExpandLabel(secret, r.prefix+"iv", initialIVLen),
+		HP:  hkdfExpandLabel(secret, r.prefix+"hp", initialKeyLen),
+	}
+	block, err := aes.NewCipher(k.Key)
+	if err != nil {
+		return nil, err
+	}
+	k.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	k.hp, err = aes.NewCipher(k.HP)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// hkdfExtract is HKDF-Extract with sha256 (rfc5869)
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpandLabel is HKDF-Expand-Label of tls 1.3 with sha256 and empty context (rfc8446)
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	fullLabel := "tls13 " + label
+	info := make([]byte, 0, 4+len(fullLabel))
+	info = append(info, byte(length>>8), byte(length), byte(len(fullLabel)))
+	info = append(info, fullLabel...)
+	info = append(info, 0)
+
+	mac := hmac.New(sha256.New, secret)
+	var out, t []byte
+	for i := byte(1); len(out) < length; i++ {
+		mac.Reset()
+		mac.Write(t)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:length]
+}
+
+// headerMask returns the mask of header protection computed from the sample
+func (k *Keys) headerMask(sample []byte) []byte {
+	mask := make([]byte, aes.BlockSize)
+	k.hp.Encrypt(mask, sample)
+	return mask
+}
+
+// nonce returns the nonce of the packet number
+func (k *Keys) nonce(pn uint64) []byte {
+	nonce := append([]byte(nil), k.IV...)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * uint(i)))
+	}
+	return nonce
+}
+
+// open removes the protection of a packet with a parsed long header. The
+// packet is modified in place and the payload decrypted is returned.
+func (k *Keys) open(h *LongHeader, packet []byte, largest int64) ([]byte, error) {
+	pnOffset := h.pnOffset
+	if len(packet) < pnOffset+4+sampleLen {
+		return nil, ErrQUICWrongSize
+	}
+	mask := k.headerMask(packet[pnOffset+4 : pnOffset+4+sampleLen])
+	packet[0] ^= mask[0] & 0x0f
+	pnLen := int(packet[0]&0x03) + 1
+	var truncated uint64
+	for i := 0; i < pnLen; i++ {
+		packet[pnOffset+i] ^= mask[1+i]
+		truncated = truncated<<8 | uint64(packet[pnOffset+i])
+	}
+	h.PacketNumberLen = pnLen
+	h.PacketNumber = decodePacketNumber(largest, truncated, pnLen)
+
+	header := packet[:pnOffset+pnLen]
+	ciphertext := packet[pnOffset+pnLen:]
+	payload, err := k.aead.Open(ciphertext[:0], k.nonce(h.PacketNumber), ciphertext, header)
+	if err != nil {
+		return nil, ErrQUICDecrypt
+	}
+	return payload, nil
+}
+
+// DecryptInitial decrypts the initial packet at the beginning of data with the
+// keys, data isn't modified. It returns the header with the packet number and
+// the payload decrypted, coalesced packets begin at PacketLen of the header.
+func DecryptInitial(data []byte, keys *Keys) (*LongHeader, []byte, error) {
+	return decryptInitial(data, keys, -1)
+}
+
+func decryptInitial(data []byte, keys *Keys, largest int64) (*LongHeader, []byte, error) {
+	h, err := ParseLongHeader(data)
+	if err != nil {
+		return h, nil, err
+	}
+	if h.Type != PacketTypeInitial {
+		return h, nil, ErrQUICNotInitial
+	}
+	packet := append([]byte(nil), data[:h.PacketLen()]...)
+	payload, err := keys.open(h, packet, largest)
+	if err != nil {
+		return h, nil, err
+	}
+	return h, payload, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package quic
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// destination connection id of the test vectors of rfc9001 and rfc9369
+var testDCID = decodeHex("8394c8f03e515708")
+
+func TestInitialKeys(t *testing.T) {
+	tests := []struct {
+		version Version
+		server  bool
+		key     string
+		iv      string
+		hp      string
+	}{
+		{Version1, false, "1f369613dd76d5467730efcbe3b1a22d", "fa044b2f42a3fd3b46fb255c", "9f50449e04a0e810283a1e9933adedd2"},
+		{Version1, true, "cf3a5331653c364c88f0f379b6067e37", "0ac1493ca1905853b0bba03e", "c206b8d9b9f0f37644430b490eeaa314"},
+		{Version2, false, "8b1a0bc121284290a29e0971b5cd045d", "91f73e2351d8fa91660e909f", "45b95e15235d6f45a6b19cbcb0294ba9"},
+	}
+	for _, test := range tests {
+		keys, err := NewInitialKeys(test.version, testDCID, test.server)
+		if err != nil {
+			t.Fatalf("%v: deriving keys: %v", test.version, err)
+		}
+		if got := hex.EncodeToString(keys.Key); got != test.key {
+			t.Errorf("%v server=%v: expected key: %v, got: %v", test.version, test.server, test.key, got)
+		}
+		if got := hex.EncodeToString(keys.IV); got != test.iv {
+			t.Errorf("%v server=%v: expected iv: %v, got: %v", test.version, test.server, test.iv, got)
+		}
+		if got := hex.EncodeToString(keys.HP); got != test.hp {
+			t.Errorf("%v server=%v: expected hp: %v, got: %v", test.version, test.server, test.hp, got)
+		}
+	}
+
+	if _, err := NewInitialKeys(Version(0x0a0a0a0a), testDCID, false); err != ErrQUICUnsupportedVersion {
+		t.Errorf("expected error: %v, got: %v", ErrQUICUnsupportedVersion, err)
+	}
+}
+
+func TestHeaderProtection(t *testing.T) {
+	keys, err := NewInitialKeys(Version1, testDCID, false)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	mask := keys.headerMask(decodeHex("d1b1c98dd7689fb8ec11d242b123dc9b"))
+	if got := hex.EncodeToString(mask[:5]); got != "437b9aec36" {
+		t.Errorf("expected mask: 437b9aec36, got: %v", got)
+	}
+}
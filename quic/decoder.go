@@ -0,0 +1,178 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package quic
+
+import (
+	"bytes"
+
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+// InitialDecoder decodes the first handshake message of the initial packets
+// of an endpoint, the ClientHello from client or the ServerHello from server.
+// Packets must be added in order of capture and it isn't safe for concurrent use.
+type InitialDecoder struct {
+	Options tlsproto.DecodeOptions
+	// Server is true if decoder decrypts packets sent by server
+	Server bool
+
+	// Version and DCID are set from the first initial packet decrypted if
+	// they aren't set, server packets need the destination connection id of
+	// the client
+	Version Version
+	DCID    []byte
+
+	// Handshake is the message decoded when crypto stream is completed
+	Handshake *tlsproto.Handshake
+
+	keys    *Keys
+	largest int64
+	stream  CryptoStream
+}
+
+// NewInitialDecoder returns a decoder of client initial packets using the options
+func NewInitialDecoder(opts tlsproto.DecodeOptions) *InitialDecoder {
+	return &InitialDecoder{
+		Options: opts,
+		largest: -1,
+	}
+}
+
+// NewServerInitialDecoder returns a decoder of server initial packets of the
+// connection initiated with the client destination connection id
+func NewServerInitialDecoder(v Version, dcid []byte, opts tlsproto.DecodeOptions) *InitialDecoder {
+	d := NewInitialDecoder(opts)
+	d.Server = true
+	d.Version = v
+	d.DCID = dcid
+	return d
+}
+
+// AddDatagram decrypts the initial packets of an udp datagram and returns
+// the handshake message once the crypto stream has all its bytes. Other
+// packets coalesced in the datagram are skipped, as well as the initial
+// packets that can't be decrypted, their error is returned if no packet of
+// the datagram is decrypted.
+//
+// After a Retry the client derives the keys from the source connection id of
+// the Retry. Decoders of server packets take it from the Retry packet and
+// decoders of client packets from the first initial packet with a new
+// destination connection id that can be decrypted with it.
+func (d *InitialDecoder) AddDatagram(data []byte) (*tlsproto.Handshake, error) {
+	if d.Handshake != nil {
+		return nil, nil
+	}
+	var skipErr error
+	decrypted := false
+	for IsLongHeader(data) {
+		h, err := ParseLongHeader(data)
+		if err != nil {
+			// rest of the datagram can't be delimited
+			skipErr = err
+			break
+		}
+		switch h.Type {
+		case PacketTypeInitial:
+			err := d.addInitial(h, data)
+			switch err {
+			case nil:
+				decrypted = true
+			case ErrQUICDecrypt, ErrQUICUnsupportedVersion:
+				skipErr = err
+			default:
+				return nil, err
+			}
+		case PacketTypeRetry:
+			if d.Server {
+				d.DCID = append([]byte(nil), h.SCID...)
+				d.keys = nil
+			}
+		}
+		data = data[h.PacketLen():]
+	}
+	if !decrypted && skipErr != nil {
+		return nil, skipErr
+	}
+	return d.decodeHandshake()
+}
+
+// addInitial decrypts an initial packet and adds its crypto frames to the stream
+func (d *InitialDecoder) addInitial(h *LongHeader, data []byte) error {
+	if d.Version != 0 && h.Version != d.Version {
+		return ErrQUICUnsupportedVersion
+	}
+	h, payload, err := d.decrypt(h, data)
+	if err != nil {
+		return err
+	}
+	if int64(h.PacketNumber) > d.largest {
+		d.largest = int64(h.PacketNumber)
+	}
+	frames, err := ParseCryptoFrames(payload)
+	if err != nil {
+		return err
+	}
+	for _, f := range frames {
+		if err := d.stream.Add(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decrypt decrypts an initial packet with the keys of the decoder. Keys are
+// derived when the first packet is decrypted, and again for client packets
+// with a new destination connection id that can't be decrypted, because
+// client changes it after a Retry. Version and DCID are set from the packet
+// that derived the keys if they weren't set.
+func (d *InitialDecoder) decrypt(h *LongHeader, data []byte) (*LongHeader, []byte, error) {
+	if d.keys != nil {
+		ph, payload, err := decryptInitial(data, d.keys, d.largest)
+		if err != ErrQUICDecrypt || d.Server || bytes.Equal(h.DCID, d.DCID) {
+			return ph, payload, err
+		}
+	}
+	v, dcid := d.Version, d.DCID
+	if v == 0 {
+		v = h.Version
+	}
+	if dcid == nil || d.keys != nil {
+		dcid = h.DCID
+	}
+	keys, err := NewInitialKeys(v, dcid, d.Server)
+	if err != nil {
+		return h, nil, err
+	}
+	ph, payload, err := decryptInitial(data, keys, d.largest)
+	if err != nil {
+		return ph, nil, err
+	}
+	d.Version, d.DCID, d.keys = v, append([]byte(nil), dcid...), keys
+	return ph, payload, nil
+}
+
+// decodeHandshake decodes the first message of the stream if it's completed
+func (d *InitialDecoder) decodeHandshake() (*tlsproto.Handshake, error) {
+	data := d.stream.Bytes()
+	_, hlen, err := tlsproto.ReadHandshakeHeader(data)
+	if err == tlsproto.ErrHandshakeWrongSize {
+		// waits for more frames
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if uint64(4+hlen) > d.stream.maxSize() {
+		return nil, ErrQUICCryptoTooLarge
+	}
+	if len(data) < 4+int(hlen) {
+		// waits for more frames
+		return nil, nil
+	}
+	handshake, err := tlsproto.NewHandshakeFromBytesWithOptions(data[:4+hlen], d.Options)
+	if err != nil {
+		return nil, err
+	}
+	d.Handshake = handshake
+	return handshake, nil
+}
@@ -0,0 +1,370 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package quic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+const (
+	pathBinFiles = "../test/data"
+)
+
+// sealInitial returns an initial packet with the frames protected with the keys
+func sealInitial(v Version, keys *Keys, dcid []byte, pn uint64, frames []byte) []byte {
+	typeBits := byte(PacketTypeInitial)
+	if v == Version2 {
+		typeBits = 0x01
+	}
+	length := 2 + len(frames) + keys.aead.Overhead()
+	header := []byte{0xc0 | typeBits<<4 | 0x01, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	header = append(header, byte(len(dcid)))
+	header = append(header, dcid...)
+	header = append(header, 0x00, 0x00, 0x40|byte(length>>8), byte(length), byte(pn>>8), byte(pn))
+	pnOffset := len(header) - 2
+
+	packet := keys.aead.Seal(header, keys.nonce(pn), frames, header)
+	mask := keys.headerMask(packet[pnOffset+4 : pnOffset+4+sampleLen])
+	packet[0] ^= mask[0] & 0x0f
+	packet[pnOffset] ^= mask[1]
+	packet[pnOffset+1] ^= mask[2]
+	return packet
+}
+
+// cryptoFrame returns a crypto frame with 2 bytes varints
+func cryptoFrame(offset int, data []byte) []byte {
+	frame := []byte{byte(FrameTypeCrypto), 0x40 | byte(offset>>8), byte(offset), 0x40 | byte(len(data)>>8), byte(len(data))}
+	return append(frame, data...)
+}
+
+// loadClientHello returns the message of a clienthello record of test data
+func loadClientHello(t *testing.T) []byte {
+	record, err := ioutil.ReadFile(pathBinFiles + "/tlsr-hsk-clienthello1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record[5:]
+}
+
+// testServerHello returns a tls 1.3 serverhello message
+func testServerHello() []byte {
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...)
+	body = append(body, 0x00, 0x13, 0x01, 0x00, 0x00, 0x06, 0x00, 0x2b, 0x00, 0x02, 0x03, 0x04)
+	return append([]byte{0x02, 0x00, 0x00, byte(len(body))}, body...)
+}
+
+func TestInitialDecoder(t *testing.T) {
+	msg := loadClientHello(t)
+	want, err := tlsproto.NewHandshakeFromBytes(msg)
+	if err != nil {
+		t.Fatal("decoding clienthello:", err)
+	}
+
+	for _, v := range []Version{Version1, Version2, VersionDraft29} {
+		keys, err := NewInitialKeys(v, testDCID, false)
+		if err != nil {
+			t.Fatal("deriving keys:", err)
+		}
+		half := len(msg) / 2
+		// ack, ping and padding frames
+		frames1 := append([]byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}, cryptoFrame(0, msg[:half+10])...)
+		frames2 := append(cryptoFrame(half, msg[half:]), make([]byte, 20)...)
+		packet1 := sealInitial(v, keys, testDCID, 0, frames1)
+		packet2 := sealInitial(v, keys, testDCID, 1, frames2)
+
+		d := NewInitialDecoder(tlsproto.DefaultDecodeOptions())
+		// second packet coalesced with a retry packet is received first
+		datagram := append(append([]byte(nil), packet2...), 0xf0, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0xaa)
+		if v == Version2 {
+			datagram[len(packet2)] = 0xc0
+		}
+		datagram[len(packet2)+1], datagram[len(packet2)+4] = byte(v>>24), byte(v)
+		datagram[len(packet2)+2], datagram[len(packet2)+3] = byte(v>>16), byte(v>>8)
+		hsk, err := d.AddDatagram(datagram)
+		if err != nil || hsk != nil {
+			t.Fatalf("%v: unexpected handshake: %v, error: %v", v, hsk, err)
+		}
+		hsk, err = d.AddDatagram(packet1)
+		if err != nil {
+			t.Fatalf("%v: decoding initial: %v", v, err)
+		}
+		if hsk == nil || hsk.ClientHello == nil {
+			t.Fatalf("%v: expected clienthello, got: %v", v, hsk)
+		}
+		if !bytes.Equal(hsk.ClientHello.Random, want.ClientHello.Random) || len(hsk.ClientHello.Extensions) != len(want.ClientHello.Extensions) {
+			t.Errorf("%v: unexpected clienthello: %v", v, hsk.ClientHello)
+		}
+		if d.Version != v || !bytes.Equal(d.DCID, testDCID) {
+			t.Errorf("%v: unexpected version: %v or dcid: %x", v, d.Version, d.DCID)
+		}
+		if !bytes.Equal(packet1, sealInitial(v, keys, testDCID, 0, frames1)) {
+			t.Errorf("%v: packet modified", v)
+		}
+	}
+}
+
+func TestInitialDecoderTooLarge(t *testing.T) {
+	keys, err := NewInitialKeys(Version1, testDCID, false)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	// clienthello declares a length greater than the crypto stream
+	frames := append(cryptoFrame(0, []byte{0x01, 0x02, 0x00, 0x00, 0x03, 0x03}), make([]byte, 20)...)
+	packet := sealInitial(Version1, keys, testDCID, 0, frames)
+
+	d := NewInitialDecoder(tlsproto.DefaultDecodeOptions())
+	if _, err := d.AddDatagram(packet); err != ErrQUICCryptoTooLarge {
+		t.Errorf("expected error: %v, got: %v", ErrQUICCryptoTooLarge, err)
+	}
+}
+
+func TestServerInitialDecoder(t *testing.T) {
+	// serverhello is the first message of server crypto stream
+	msg := testServerHello()
+
+	keys, err := NewInitialKeys(Version1, testDCID, true)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	scid := []byte{0x01, 0x02, 0x03, 0x04}
+	packet := sealInitial(Version1, keys, scid, 0x1234, cryptoFrame(0, msg))
+
+	// client keys can't decrypt it
+	if _, err := NewInitialDecoder(tlsproto.DefaultDecodeOptions()).AddDatagram(packet); err != ErrQUICDecrypt {
+		t.Errorf("expected error: %v, got: %v", ErrQUICDecrypt, err)
+	}
+	h, _, err := DecryptInitial(packet, keys)
+	if err != nil {
+		t.Fatal("decrypting initial:", err)
+	}
+	if h.Version != Version1 || h.Type != PacketTypeInitial || h.PacketNumber != 0x1234 || h.PacketNumberLen != 2 || !bytes.Equal(h.DCID, scid) {
+		t.Errorf("unexpected header: %v", h)
+	}
+
+	d := NewServerInitialDecoder(Version1, testDCID, tlsproto.DefaultDecodeOptions())
+	hsk, err := d.AddDatagram(packet)
+	if err != nil {
+		t.Fatal("decoding server initial:", err)
+	}
+	if hsk == nil || hsk.ServerHello == nil || hsk.ServerHello.NegotiatedVersion() != 0x0304 {
+		t.Errorf("expected tls 1.3 serverhello, got: %v", hsk)
+	}
+}
+
+func TestInitialDecoderSkip(t *testing.T) {
+	msg := loadClientHello(t)
+	keys, err := NewInitialKeys(Version1, testDCID, false)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	other, err := NewInitialKeys(Version1, []byte{0x01, 0x02, 0x03, 0x04}, false)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	bad := sealInitial(Version1, other, testDCID, 0, cryptoFrame(0, msg[:100]))
+	packet := sealInitial(Version1, keys, testDCID, 1, cryptoFrame(0, msg))
+
+	d := NewInitialDecoder(tlsproto.DefaultDecodeOptions())
+	if _, err := d.AddDatagram(bad); err != ErrQUICDecrypt {
+		t.Errorf("expected error: %v, got: %v", ErrQUICDecrypt, err)
+	}
+	if d.DCID != nil {
+		t.Errorf("unexpected dcid: %x", d.DCID)
+	}
+	// packet that can't be decrypted doesn't abort the datagram
+	hsk, err := d.AddDatagram(append(append([]byte(nil), bad...), packet...))
+	if err != nil || hsk == nil || hsk.ClientHello == nil {
+		t.Errorf("expected clienthello, got: %v, error: %v", hsk, err)
+	}
+}
+
+func TestInitialDecoderRetry(t *testing.T) {
+	msg := loadClientHello(t)
+	newDCID := []byte{0xf0, 0x67, 0xa5, 0x50, 0x2a, 0x42, 0x62, 0xb5}
+	keys, err := NewInitialKeys(Version1, testDCID, false)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	retryKeys, err := NewInitialKeys(Version1, newDCID, false)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	// clienthello is sent again after retry with the new connection id
+	d := NewInitialDecoder(tlsproto.DefaultDecodeOptions())
+	if hsk, err := d.AddDatagram(sealInitial(Version1, keys, testDCID, 0, cryptoFrame(0, msg[:100]))); err != nil || hsk != nil {
+		t.Fatalf("unexpected handshake: %v, error: %v", hsk, err)
+	}
+	hsk, err := d.AddDatagram(sealInitial(Version1, retryKeys, newDCID, 1, cryptoFrame(0, msg)))
+	if err != nil || hsk == nil || hsk.ClientHello == nil {
+		t.Fatalf("expected clienthello after retry, got: %v, error: %v", hsk, err)
+	}
+	if !bytes.Equal(d.DCID, newDCID) {
+		t.Errorf("expected dcid: %x, got: %x", newDCID, d.DCID)
+	}
+
+	// server sends a retry with its connection id and then its initial
+	serverKeys, err := NewInitialKeys(Version1, newDCID, true)
+	if err != nil {
+		t.Fatal("deriving keys:", err)
+	}
+	retry := []byte{0xf0, 0x00, 0x00, 0x00, 0x01, 0x00, byte(len(newDCID))}
+	retry = append(append(retry, newDCID...), make([]byte, 16+4)...)
+	d = NewServerInitialDecoder(Version1, testDCID, tlsproto.DefaultDecodeOptions())
+	if hsk, err := d.AddDatagram(retry); err != nil || hsk != nil {
+		t.Fatalf("unexpected handshake: %v, error: %v", hsk, err)
+	}
+	hsk, err = d.AddDatagram(sealInitial(Version1, serverKeys, nil, 0, cryptoFrame(0, testServerHello())))
+	if err != nil || hsk == nil || hsk.ServerHello == nil {
+		t.Errorf("expected serverhello after retry, got: %v, error: %v", hsk, err)
+	}
+}
+
+// testVectorFrame is the crypto frame with the clienthello of the client
+// initial packet of rfc9001 appendix A.2 and rfc9369 appendix A.2
+var testVectorFrame = decodeHex(
+	"060040f1010000ed0303ebf8fa56f12939b9584a3896472ec40bb863cfd3e868" +
+		"04fe3a47f06a2b69484c00000413011302010000c000000010000e00000b6578" +
+		"616d706c652e636f6dff01000100000a00080006001d00170018001000070005" +
+		"04616c706e000500050100000000003300260024001d00209370b2c9caa47fba" +
+		"baf4fe5cef4f9b6aa0046a40e0a5c1ae8dbcc4df3fa4cf00002b000302030400" +
+		"0d0010000e0403050306030203080408050806002d00020101001c0002400100" +
+		"3900320408ffffffffffffffff05048000ffff07048000ffff08011001048000" +
+		"75300901100f088394c8f03e51570806048000ffff")
+
+func TestInitialDecoderVectors(t *testing.T) {
+	tests := []struct {
+		version   Version
+		header    string
+		sample    string
+		protected string
+	}{
+		{Version1, "c300000001088394c8f03e5157080000449e00000002", "d1b1c98dd7689fb8ec11d242b123dc9b", "c000000001088394c8f03e5157080000449e7b9aec34"},
+		{Version2, "d36b3343cf088394c8f03e5157080000449e00000002", "ffe67b6abcdb4298b485dd04de806071", "d76b3343cf088394c8f03e5157080000449ea0c95e82"},
+	}
+	for _, test := range tests {
+		keys, err := NewInitialKeys(test.version, testDCID, false)
+		if err != nil {
+			t.Fatal("deriving keys:", err)
+		}
+		// payload is padded to 1162 bytes and the packet number has 4 bytes
+		header := decodeHex(test.header)
+		pnOffset := len(header) - 4
+		payload := make([]byte, 1162)
+		copy(payload, testVectorFrame)
+		packet := keys.aead.Seal(header, keys.nonce(2), payload, header)
+		sample := packet[pnOffset+4 : pnOffset+4+sampleLen]
+		if got := hex.EncodeToString(sample); got != test.sample {
+			t.Errorf("%v: expected sample: %v, got: %v", test.version, test.sample, got)
+		}
+		mask := keys.headerMask(sample)
+		packet[0] ^= mask[0] & 0x0f
+		for i := 0; i < 4; i++ {
+			packet[pnOffset+i] ^= mask[1+i]
+		}
+		if got := hex.EncodeToString(packet[:len(header)]); got != test.protected {
+			t.Errorf("%v: expected header: %v, got: %v", test.version, test.protected, got)
+		}
+		if len(packet) != 1200 {
+			t.Errorf("%v: expected packet len: 1200, got: %v", test.version, len(packet))
+		}
+
+		h, decrypted, err := DecryptInitial(packet, keys)
+		if err != nil {
+			t.Fatalf("%v: decrypting packet: %v", test.version, err)
+		}
+		if h.PacketNumber != 2 || h.PacketNumberLen != 4 || h.PacketLen() != len(packet) {
+			t.Errorf("%v: unexpected header: %v", test.version, h)
+		}
+		if !bytes.Equal(decrypted, payload) {
+			t.Errorf("%v: unexpected payload: %x", test.version, decrypted)
+		}
+
+		d := NewInitialDecoder(tlsproto.DefaultDecodeOptions())
+		hsk, err := d.AddDatagram(packet)
+		if err != nil || hsk == nil || hsk.ClientHello == nil {
+			t.Fatalf("%v: expected clienthello, got: %v, error: %v", test.version, hsk, err)
+		}
+		if info := hsk.ClientHello.ExtInfo; info == nil || info.SNI != "example.com" {
+			t.Errorf("%v: expected sni: example.com, got: %v", test.version, info)
+		}
+	}
+}
+
+func TestParseLongHeader(t *testing.T) {
+	// version negotiation
+	h, err := ParseLongHeader([]byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x01, 0xaa, 0x00, 0x00, 0x00, 0x00, 0x01})
+	if err != ErrQUICUnsupportedVersion || h == nil || !bytes.Equal(h.DCID, []byte{0xaa}) {
+		t.Errorf("expected version negotiation, got: %v, error: %v", h, err)
+	}
+	if _, err := ParseLongHeader([]byte{0x40, 0x01}); err != ErrQUICShortHeader {
+		t.Errorf("expected error: %v, got: %v", ErrQUICShortHeader, err)
+	}
+	if _, err := ParseLongHeader([]byte{0xc0, 0x00, 0x00, 0x00, 0x01, 0x15}); err != ErrQUICWrongSize {
+		t.Errorf("expected error: %v, got: %v", ErrQUICWrongSize, err)
+	}
+	if _, err := ParseLongHeader([]byte{0xc0, 0x00, 0x00, 0x00, 0x01, 0x15, 0x00}); err != ErrQUICConnectionID {
+		t.Errorf("expected error: %v, got: %v", ErrQUICConnectionID, err)
+	}
+	// initial with a length greater than packet
+	if _, err := ParseLongHeader([]byte{0xc0, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x10, 0x00}); err != ErrQUICWrongSize {
+		t.Errorf("expected error: %v, got: %v", ErrQUICWrongSize, err)
+	}
+
+	if pn := decodePacketNumber(0xa82f30ea, 0x9b32, 2); pn != 0xa82f9b32 {
+		t.Errorf("expected packet number: %#x, got: %#x", 0xa82f9b32, pn)
+	}
+}
+
+func TestCryptoStream(t *testing.T) {
+	payload := []byte{0x01, 0x00}
+	payload = append(payload, cryptoFrame(4, []byte{0x05, 0x06})...)
+	payload = append(payload, 0x03, 0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	payload = append(payload, cryptoFrame(0, []byte{0x01, 0x02, 0x03})...)
+	frames, err := ParseCryptoFrames(payload)
+	if err != nil {
+		t.Fatal("parsing frames:", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("expected frames: 2, got: %v", len(frames))
+	}
+	s := CryptoStream{}
+	for _, f := range frames {
+		s.Add(f)
+	}
+	if !bytes.Equal(s.Bytes(), []byte{0x01, 0x02, 0x03}) {
+		t.Errorf("unexpected stream: %x", s.Bytes())
+	}
+	s.Add(CryptoFrame{Offset: 2, Data: []byte{0x03, 0x04}})
+	if !bytes.Equal(s.Bytes(), []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}) {
+		t.Errorf("unexpected stream: %x", s.Bytes())
+	}
+	// zero value is limited to the default size
+	huge := []byte{byte(FrameTypeCrypto), 0xc0, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x01, 0xaa}
+	frames, err = ParseCryptoFrames(huge)
+	if err != nil || len(frames) != 1 || frames[0].Offset != 0x1000000000 {
+		t.Fatalf("unexpected frames: %v, error: %v", frames, err)
+	}
+	var zero CryptoStream
+	if err := zero.Add(frames[0]); err != ErrQUICCryptoTooLarge {
+		t.Errorf("expected error: %v, got: %v", ErrQUICCryptoTooLarge, err)
+	}
+	if err := zero.Add(CryptoFrame{Offset: DefaultMaxCryptoSize, Data: []byte{0x01}}); err != ErrQUICCryptoTooLarge {
+		t.Errorf("expected error: %v, got: %v", ErrQUICCryptoTooLarge, err)
+	}
+	s.MaxSize = 8
+	if err := s.Add(CryptoFrame{Offset: 7, Data: []byte{0x01, 0x02}}); err != ErrQUICCryptoTooLarge {
+		t.Errorf("expected error: %v, got: %v", ErrQUICCryptoTooLarge, err)
+	}
+
+	if _, err := ParseCryptoFrames([]byte{0x08, 0x00}); err != ErrQUICFrameUnexpected {
+		t.Errorf("expected error: %v, got: %v", ErrQUICFrameUnexpected, err)
+	}
+	if _, err := ParseCryptoFrames(cryptoFrame(0, []byte{0x01})[:5]); err != ErrQUICFrameBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrQUICFrameBadLength, err)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package quic
+
+import (
+	"errors"
+)
+
+// common errors in quic packets
+var (
+	ErrQUICWrongSize          = errors.New("quic packet is of wrong size")
+	ErrQUICShortHeader        = errors.New("quic packet hasn't a long header")
+	ErrQUICUnsupportedVersion = errors.New("quic packet is of unsupported version")
+	ErrQUICNotInitial         = errors.New("quic packet isn't an initial packet")
+	ErrQUICConnectionID       = errors.New("quic packet has a connection id too long")
+	ErrQUICDecrypt            = errors.New("quic packet can't be decrypted")
+)
+
+// common errors in quic frames
+var (
+	ErrQUICFrameBadLength  = errors.New("quic frame has a malformed length")
+	ErrQUICFrameUnexpected = errors.New("quic frame isn't allowed in initial packets")
+	ErrQUICCryptoTooLarge  = errors.New("quic crypto stream exceeds the maximum size allowed")
+)
@@ -0,0 +1,204 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package quic
+
+import (
+	"fmt"
+)
+
+// FrameType is the type of a quic frame
+type FrameType uint64
+
+// FrameType values allowed in initial packets
+const (
+	FrameTypePadding         FrameType = 0x00
+	FrameTypePing            FrameType = 0x01
+	FrameTypeAck             FrameType = 0x02
+	FrameTypeAckECN          FrameType = 0x03
+	FrameTypeCrypto          FrameType = 0x06
+	FrameTypeConnectionClose FrameType = 0x1c
+)
+
+func (f FrameType) getDesc() string {
+	switch f {
+	case FrameTypePadding:
+		return "padding"
+	case FrameTypePing:
+		return "ping"
+	case FrameTypeAck:
+		return "ack"
+	case FrameTypeAckECN:
+		return "ack_ecn"
+	case FrameTypeCrypto:
+		return "crypto"
+	case FrameTypeConnectionClose:
+		return "connection_close"
+	default:
+		return "unknown"
+	}
+}
+
+func (f FrameType) String() string {
+	return fmt.Sprintf("%s(%d)", f.getDesc(), f)
+}
+
+// CryptoFrame is a frame with data of the tls handshake at offset of the crypto stream
+type CryptoFrame struct {
+	Offset uint64 `json:"offset"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+// ParseCryptoFrames returns the crypto frames of the payload of an initial
+// packet, the rest of frames allowed in initial packets are skipped
+func ParseCryptoFrames(payload []byte) ([]CryptoFrame, error) {
+	var frames []CryptoFrame
+	for len(payload) > 0 {
+		v, n := readVarint(payload)
+		if n == 0 {
+			return frames, ErrQUICFrameBadLength
+		}
+		ftype := FrameType(v)
+		payload = payload[n:]
+		switch ftype {
+		case FrameTypePadding, FrameTypePing:
+		case FrameTypeAck, FrameTypeAckECN:
+			// largest, delay, range count and first range
+			values, err := readVarints(payload, 4)
+			if err != nil {
+				return frames, err
+			}
+			count := 2 * values[2]
+			if ftype == FrameTypeAckECN {
+				count += 3
+			}
+			payload, err = skipVarints(payload, 4+count)
+			if err != nil {
+				return frames, err
+			}
+		case FrameTypeCrypto:
+			values, err := readVarints(payload, 2)
+			if err != nil {
+				return frames, err
+			}
+			payload, _ = skipVarints(payload, 2)
+			if uint64(len(payload)) < values[1] {
+				return frames, ErrQUICFrameBadLength
+			}
+			frames = append(frames, CryptoFrame{Offset: values[0], Data: payload[:values[1]]})
+			payload = payload[values[1]:]
+		case FrameTypeConnectionClose:
+			// error code, frame type and reason phrase
+			values, err := readVarints(payload, 3)
+			if err != nil {
+				return frames, err
+			}
+			payload, _ = skipVarints(payload, 3)
+			if uint64(len(payload)) < values[2] {
+				return frames, ErrQUICFrameBadLength
+			}
+			payload = payload[values[2]:]
+		default:
+			return frames, ErrQUICFrameUnexpected
+		}
+	}
+	return frames, nil
+}
+
+// readVarints reads n variable-length integers
+func readVarints(data []byte, n int) ([]uint64, error) {
+	values := make([]uint64, n)
+	for i := range values {
+		v, l := readVarint(data)
+		if l == 0 {
+			return nil, ErrQUICFrameBadLength
+		}
+		values[i] = v
+		data = data[l:]
+	}
+	return values, nil
+}
+
+// skipVarints returns data after n variable-length integers
+func skipVarints(data []byte, n uint64) ([]byte, error) {
+	for i := uint64(0); i < n; i++ {
+		_, l := readVarint(data)
+		if l == 0 {
+			return nil, ErrQUICFrameBadLength
+		}
+		data = data[l:]
+	}
+	return data, nil
+}
+
+// DefaultMaxCryptoSize is the maximum size of crypto streams that don't set it,
+// memory of the stream is allocated up to the offset of the frames
+const DefaultMaxCryptoSize = 1 << 16
+
+// cryptoRange is a range [start, end) of the crypto stream received
+type cryptoRange struct {
+	start, end uint64
+}
+
+// CryptoStream reassembles the data of the crypto frames of an encryption
+// level, frames may arrive out of order, overlapped or retransmitted
+type CryptoStream struct {
+	// MaxSize is the maximum offset of the data, zero means DefaultMaxCryptoSize
+	MaxSize uint64
+
+	buf    []byte
+	ranges []cryptoRange
+}
+
+// maxSize returns the maximum offset of the data
+func (s *CryptoStream) maxSize() uint64 {
+	if s.MaxSize == 0 {
+		return DefaultMaxCryptoSize
+	}
+	return s.MaxSize
+}
+
+// Add copies the data of the frame to the stream
+func (s *CryptoStream) Add(f CryptoFrame) error {
+	start, end := f.Offset, f.Offset+uint64(len(f.Data))
+	if end < start || end > s.maxSize() {
+		return ErrQUICCryptoTooLarge
+	}
+	if uint64(len(s.buf)) < end {
+		s.buf = append(s.buf, make([]byte, int(end)-len(s.buf))...)
+	}
+	copy(s.buf[start:end], f.Data)
+	if start == end {
+		return nil
+	}
+	merged := s.ranges[:0]
+	for _, r := range s.ranges {
+		if r.end < start || r.start > end {
+			merged = append(merged, r)
+			continue
+		}
+		if r.start < start {
+			start = r.start
+		}
+		if r.end > end {
+			end = r.end
+		}
+	}
+	s.ranges = append(merged, cryptoRange{start: start, end: end})
+	return nil
+}
+
+// Bytes returns the data received from the beginning of the stream without gaps
+func (s *CryptoStream) Bytes() []byte {
+	for _, r := range s.ranges {
+		if r.start == 0 {
+			return s.buf[:r.end]
+		}
+	}
+	return nil
+}
+
+// Reset discards the data of the stream
+func (s *CryptoStream) Reset() {
+	s.buf = s.buf[:0]
+	s.ranges = s.ranges[:0]
+}
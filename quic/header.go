@@ -0,0 +1,155 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package quic
+
+import (
+	"fmt"
+)
+
+// Bits of the first byte of quic packets
+const (
+	headerFormLong = 0x80
+	headerFixedBit = 0x40
+)
+
+// MaxConnectionIDLen is the maximum length of connection ids in version 1 and 2
+const MaxConnectionIDLen = 20
+
+// LongHeader is the header of a long header packet (rfc9000). The packet
+// number is protected, so it's only set when packet is decrypted.
+type LongHeader struct {
+	Type    PacketType `json:"type"`
+	Version Version    `json:"version"`
+	DCID    []byte     `json:"dcid,omitempty"`
+	SCID    []byte     `json:"scid,omitempty"`
+	// Token is only present in initial packets
+	Token []byte `json:"token,omitempty"`
+	// Length is the length of the packet number and the payload, retry
+	// packets don't have it
+	Length uint64 `json:"length"`
+
+	PacketNumber    uint64 `json:"packetNumber"`
+	PacketNumberLen int    `json:"packetNumberLen"`
+
+	// pnOffset is the offset of the packet number in the packet
+	pnOffset int
+}
+
+func (h *LongHeader) String() string {
+	return fmt.Sprintf("%s %s dcid=%x scid=%x pn=%d (len=%d)", h.Version, h.Type, h.DCID, h.SCID, h.PacketNumber, h.Length)
+}
+
+// IsLongHeader returns true if the first byte of data is a long header
+func IsLongHeader(data []byte) bool {
+	return len(data) > 0 && data[0]&headerFormLong != 0
+}
+
+// PacketLen returns the length of the packet, coalesced packets of a datagram
+// begin after it
+func (h *LongHeader) PacketLen() int {
+	return h.pnOffset + int(h.Length)
+}
+
+// ParseLongHeader parses the unprotected fields of a long header packet. It
+// returns ErrQUICUnsupportedVersion for version negotiation packets and
+// unknown versions, their format is unknown after the connection ids.
+func ParseLongHeader(data []byte) (*LongHeader, error) {
+	if !IsLongHeader(data) {
+		return nil, ErrQUICShortHeader
+	}
+	if len(data) < 7 {
+		return nil, ErrQUICWrongSize
+	}
+	h := &LongHeader{}
+	h.Version = Version(uint32(data[1])<<24 | uint32(data[2])<<16 | uint32(data[3])<<8 | uint32(data[4]))
+	off := 5
+	var err error
+	h.DCID, off, err = readConnectionID(data, off)
+	if err != nil {
+		return nil, err
+	}
+	h.SCID, off, err = readConnectionID(data, off)
+	if err != nil {
+		return nil, err
+	}
+	if !h.Version.IsValid() {
+		return h, ErrQUICUnsupportedVersion
+	}
+	h.Type = packetType(h.Version, data[0]>>4)
+	if h.Type == PacketTypeRetry {
+		h.pnOffset = off
+		h.Length = uint64(len(data) - off)
+		return h, nil
+	}
+	if h.Type == PacketTypeInitial {
+		tokenLen, n := readVarint(data[off:])
+		if n == 0 || uint64(len(data)-off-n) < tokenLen {
+			return nil, ErrQUICWrongSize
+		}
+		off += n
+		if tokenLen > 0 {
+			h.Token = data[off : off+int(tokenLen)]
+		}
+		off += int(tokenLen)
+	}
+	length, n := readVarint(data[off:])
+	if n == 0 {
+		return nil, ErrQUICWrongSize
+	}
+	off += n
+	if uint64(len(data)-off) < length {
+		return nil, ErrQUICWrongSize
+	}
+	h.Length = length
+	h.pnOffset = off
+	return h, nil
+}
+
+// readConnectionID reads a connection id with its length at offset
+func readConnectionID(data []byte, off int) ([]byte, int, error) {
+	if len(data) < off+1 {
+		return nil, off, ErrQUICWrongSize
+	}
+	cidLen := int(data[off])
+	off++
+	if cidLen > MaxConnectionIDLen {
+		return nil, off, ErrQUICConnectionID
+	}
+	if len(data) < off+cidLen {
+		return nil, off, ErrQUICWrongSize
+	}
+	return data[off : off+cidLen], off + cidLen, nil
+}
+
+// readVarint reads a variable-length integer, it returns the number of bytes
+// read or zero if data is too short
+func readVarint(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	n := 1 << (data[0] >> 6)
+	if len(data) < n {
+		return 0, 0
+	}
+	v := uint64(data[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(data[i])
+	}
+	return v, n
+}
+
+// decodePacketNumber reconstructs a packet number truncated to pnLen bytes
+// using the largest packet number received (rfc9000 appendix A.3)
+func decodePacketNumber(largest int64, truncated uint64, pnLen int) uint64 {
+	expected := uint64(largest + 1)
+	win := uint64(1) << (8 * uint(pnLen))
+	hwin := win / 2
+	candidate := (expected &^ (win - 1)) | truncated
+	if candidate+hwin <= expected && candidate < (1<<62)-win {
+		return candidate + win
+	}
+	if candidate > expected+hwin && candidate >= win {
+		return candidate - win
+	}
+	return candidate
+}
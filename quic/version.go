@@ -0,0 +1,92 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package quic
+
+import "fmt"
+
+// Version of quic in long headers
+type Version uint32
+
+// Version supported values
+const (
+	Version1       Version = 0x00000001
+	Version2       Version = 0x6b3343cf
+	VersionDraft29 Version = 0xff00001d
+)
+
+// versionReg stores description, salt of initial secrets and prefix of the
+// labels used to derive the keys of each version
+var versionReg = map[Version]struct {
+	desc   string
+	salt   []byte
+	prefix string
+}{
+	Version1: {"v1", []byte{
+		0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+		0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}, "quic "},
+	Version2: {"v2", []byte{
+		0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93,
+		0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9}, "quicv2 "},
+	VersionDraft29: {"draft-29", []byte{
+		0xaf, 0xbf, 0xec, 0x28, 0x99, 0x93, 0xd2, 0x4c, 0x9e, 0x97,
+		0x86, 0xf1, 0x9c, 0x61, 0x11, 0xe0, 0x43, 0x90, 0xa8, 0x99}, "quic "},
+}
+
+func (v Version) getDesc() string {
+	if v == 0 {
+		return "version_negotiation"
+	}
+	if r, ok := versionReg[v]; ok {
+		return r.desc
+	}
+	return "unknown"
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%s(%#x)", v.getDesc(), uint32(v))
+}
+
+// IsValid method checks if it's a supported version
+func (v Version) IsValid() bool {
+	_, ok := versionReg[v]
+	return ok
+}
+
+// PacketType is the type of a long header packet
+type PacketType uint8
+
+// PacketType possible values, they are the values encoded in version 1
+const (
+	PacketTypeInitial   PacketType = 0
+	PacketType0RTT      PacketType = 1
+	PacketTypeHandshake PacketType = 2
+	PacketTypeRetry     PacketType = 3
+)
+
+func (t PacketType) getDesc() string {
+	switch t {
+	case PacketTypeInitial:
+		return "initial"
+	case PacketType0RTT:
+		return "0-rtt"
+	case PacketTypeHandshake:
+		return "handshake"
+	case PacketTypeRetry:
+		return "retry"
+	default:
+		return "unknown"
+	}
+}
+
+func (t PacketType) String() string {
+	return fmt.Sprintf("%s(%d)", t.getDesc(), t)
+}
+
+// packetType returns the type of the bits of the first byte of a long header,
+// version 2 encodes the types rotated one position (rfc9369)
+func packetType(v Version, bits byte) PacketType {
+	if v == Version2 {
+		return PacketType((bits - 1) & 0x03)
+	}
+	return PacketType(bits & 0x03)
+}
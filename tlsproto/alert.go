@@ -5,6 +5,7 @@ package tlsproto
 import (
 	"fmt"
 
+	"github.com/google/gopacket"
 	"github.com/luisguillenc/tlslayer"
 )
 
@@ -44,6 +45,7 @@ const (
 	AlertProtocolVersion        AlertDescription = 70
 	AlertInsufficientSecurity   AlertDescription = 71
 	AlertInternalError          AlertDescription = 80
+	AlertInappropriateFallback  AlertDescription = 86
 	AlertUserCanceled           AlertDescription = 90
 	AlertNoRenegotiation        AlertDescription = 100
 	AlertUnSupportedExtension   AlertDescription = 110
@@ -73,6 +75,7 @@ var alertDescriptionReg = map[AlertDescription]string{
 	AlertProtocolVersion:        "protocol_version",
 	AlertInsufficientSecurity:   "insufficient_security",
 	AlertInternalError:          "internal_error",
+	AlertInappropriateFallback:  "inappropriate_fallback",
 	AlertUserCanceled:           "user_canceled",
 	AlertNoRenegotiation:        "no_renegotiation",
 	AlertUnSupportedExtension:   "unsupported_extension",
@@ -120,6 +123,8 @@ type Alert struct {
 
 	Level       AlertLevel       `json:"level"`
 	Description AlertDescription `json:"description"`
+
+	contents []byte
 }
 
 func (alert *Alert) String() string {
@@ -135,21 +140,70 @@ func (alert *Alert) GetContentType() tlslayer.ContentType {
 
 // NewAlertFromBytes creates an alert from a byte slice with the payload
 func NewAlertFromBytes(payload []byte) (*Alert, error) {
+	alert := &Alert{}
+	if err := alert.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	}
+
+	return alert, nil
+}
+
+// DecodeFromBytes decodes an alert from a byte slice with the payload reusing the struct
+func (alert *Alert) DecodeFromBytes(payload []byte, df gopacket.DecodeFeedback) error {
 	if len(payload) < 2 {
-		return nil, ErrWrowngLenPayload
+		return ErrWrowngLenPayload
 	}
 
 	level := AlertLevel(payload[0])
 	if !level.IsValid() {
-		return nil, ErrAlertInvalidLevel
+		return ErrAlertInvalidLevel
 	}
 	description := AlertDescription(payload[1])
 	if !description.IsValid() {
-		return nil, ErrAlertInvalidDesc
+		return ErrAlertInvalidDesc
 	}
-	alert := &Alert{Level: level, Description: description}
+	alert.Level = level
+	alert.Description = description
+	alert.contents = payload
 
-	return alert, nil
+	return nil
+}
+
+// CanDecode satisfaces the interface
+func (alert *Alert) CanDecode() gopacket.LayerClass {
+	return LayerTypeAlert
+}
+
+// LayerType satisfaces the interface
+func (alert *Alert) LayerType() gopacket.LayerType {
+	return LayerTypeAlert
+}
+
+// NextLayerType satisfaces the interface, alert is the last layer
+func (alert *Alert) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+// LayerContents satisfaces the interface
+func (alert *Alert) LayerContents() []byte {
+	return alert.contents
+}
+
+// LayerPayload satisfaces the interface, alerts have no payload
+func (alert *Alert) LayerPayload() []byte {
+	return nil
+}
+
+// decodeAlertLayer decodes the byte slice and add alert layer to packet builder
+func decodeAlertLayer(data []byte, p gopacket.PacketBuilder) error {
+	alert := &Alert{}
+	err := alert.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(alert)
+
+	return nil
 }
 
 // NewAlertFromRecord creates an alert from a TLS Record
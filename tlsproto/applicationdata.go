@@ -5,6 +5,7 @@ package tlsproto
 import (
 	"fmt"
 
+	"github.com/google/gopacket"
 	"github.com/luisguillenc/tlslayer"
 )
 
@@ -31,6 +32,47 @@ func NewApplicationDataFromBytes(payload []byte) (*ApplicationData, error) {
 	return appdata, nil
 }
 
+// DecodeFromBytes sets the data of the struct with the payload
+func (appdata *ApplicationData) DecodeFromBytes(payload []byte, df gopacket.DecodeFeedback) error {
+	appdata.Data = payload
+
+	return nil
+}
+
+// CanDecode satisfaces the interface
+func (appdata *ApplicationData) CanDecode() gopacket.LayerClass {
+	return LayerTypeApplicationData
+}
+
+// LayerType satisfaces the interface
+func (appdata *ApplicationData) LayerType() gopacket.LayerType {
+	return LayerTypeApplicationData
+}
+
+// NextLayerType satisfaces the interface, applicationdata is the last layer
+func (appdata *ApplicationData) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+// LayerContents satisfaces the interface
+func (appdata *ApplicationData) LayerContents() []byte {
+	return appdata.Data
+}
+
+// LayerPayload satisfaces the interface, data is encrypted so it isn't a payload
+func (appdata *ApplicationData) LayerPayload() []byte {
+	return nil
+}
+
+// decodeApplicationDataLayer decodes the byte slice and add applicationdata layer to packet builder
+func decodeApplicationDataLayer(data []byte, p gopacket.PacketBuilder) error {
+	appdata := &ApplicationData{}
+	appdata.DecodeFromBytes(data, p)
+	p.AddLayer(appdata)
+
+	return nil
+}
+
 // NewApplicationDataFromRecord creates an application data from a TLS Record
 func NewApplicationDataFromRecord(tlsr *tlslayer.TLSRecord) (*ApplicationData, error) {
 	if tlsr.Type != tlslayer.ContentTypeApplicationData {
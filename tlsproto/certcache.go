@@ -0,0 +1,82 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"sync"
+)
+
+// DefaultCertificateCacheSize is the size of the cache used by DefaultDecodeOptions
+const DefaultCertificateCacheSize = 4096
+
+// CertificateCache stores parsed certificates by sha256 fingerprint, so
+// repeated chains are parsed once. Certificates returned from a cache are
+// shared by all the handshakes that contain them, so they must be treated as
+// read-only. It's safe for concurrent use.
+type CertificateCache struct {
+	size int
+
+	mu    sync.RWMutex
+	certs map[[sha256.Size]byte]*x509.Certificate
+}
+
+// NewCertificateCache returns a cache of size certificates, when it's full
+// the cache is flushed
+func NewCertificateCache(size int) *CertificateCache {
+	return &CertificateCache{
+		size:  size,
+		certs: make(map[[sha256.Size]byte]*x509.Certificate),
+	}
+}
+
+// defaultCertCache is the cache shared by decoders using DefaultDecodeOptions
+var defaultCertCache = NewCertificateCache(DefaultCertificateCacheSize)
+
+// Parse returns the certificate from cache or parses and stores it. A nil
+// cache parses the certificate without caching it.
+func (c *CertificateCache) Parse(fingerprint []byte, raw []byte) (*x509.Certificate, error) {
+	if c == nil || c.size <= 0 || len(fingerprint) != sha256.Size {
+		return x509.ParseCertificate(raw)
+	}
+	var key [sha256.Size]byte
+	copy(key[:], fingerprint)
+
+	c.mu.RLock()
+	cert, ok := c.certs[key]
+	c.mu.RUnlock()
+	if ok {
+		return cert, nil
+	}
+	// parsed certificate references its input, so it can't alias a packet buffer
+	der := make([]byte, len(raw))
+	copy(der, raw)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if len(c.certs) >= c.size {
+		c.certs = make(map[[sha256.Size]byte]*x509.Certificate)
+	}
+	c.certs[key] = cert
+	c.mu.Unlock()
+
+	return cert, nil
+}
+
+// Flush removes all the certificates from the cache
+func (c *CertificateCache) Flush() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.certs = make(map[[sha256.Size]byte]*x509.Certificate)
+	c.mu.Unlock()
+}
+
+// FlushCertificateCache removes all the certificates from the cache used by DefaultDecodeOptions
+func FlushCertificateCache() {
+	defaultCertCache.Flush()
+}
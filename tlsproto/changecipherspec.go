@@ -5,6 +5,7 @@ package tlsproto
 import (
 	"fmt"
 
+	"github.com/google/gopacket"
 	"github.com/luisguillenc/tlslayer"
 )
 
@@ -37,6 +38,8 @@ type ChangeCipherSpec struct {
 	TLSMessage
 
 	Type CipherSpecType `json:"type"`
+
+	contents []byte
 }
 
 func (ccs *ChangeCipherSpec) String() string {
@@ -50,18 +53,65 @@ func (ccs *ChangeCipherSpec) GetType() tlslayer.ContentType {
 
 // NewChangeCipherSpecFromBytes creates a changecipherspec from a byte slice with the payload
 func NewChangeCipherSpecFromBytes(payload []byte) (*ChangeCipherSpec, error) {
+	ccs := &ChangeCipherSpec{}
+	if err := ccs.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	}
+
+	return ccs, nil
+}
+
+// DecodeFromBytes decodes a changecipherspec from a byte slice with the payload reusing the struct
+func (ccs *ChangeCipherSpec) DecodeFromBytes(payload []byte, df gopacket.DecodeFeedback) error {
 	if len(payload) < 1 {
-		return nil, ErrWrowngLenPayload
+		return ErrWrowngLenPayload
 	}
 
 	ctype := CipherSpecType(payload[0])
 	if !ctype.IsValid() {
-		return nil, ErrCCSInvalidValue
+		return ErrCCSInvalidValue
 	}
+	ccs.Type = ctype
+	ccs.contents = payload
 
-	ccs := &ChangeCipherSpec{Type: ctype}
+	return nil
+}
 
-	return ccs, nil
+// CanDecode satisfaces the interface
+func (ccs *ChangeCipherSpec) CanDecode() gopacket.LayerClass {
+	return LayerTypeChangeCipherSpec
+}
+
+// LayerType satisfaces the interface
+func (ccs *ChangeCipherSpec) LayerType() gopacket.LayerType {
+	return LayerTypeChangeCipherSpec
+}
+
+// NextLayerType satisfaces the interface, changecipherspec is the last layer
+func (ccs *ChangeCipherSpec) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+// LayerContents satisfaces the interface
+func (ccs *ChangeCipherSpec) LayerContents() []byte {
+	return ccs.contents
+}
+
+// LayerPayload satisfaces the interface, changecipherspec has no payload
+func (ccs *ChangeCipherSpec) LayerPayload() []byte {
+	return nil
+}
+
+// decodeChangeCipherSpecLayer decodes the byte slice and add changecipherspec layer to packet builder
+func decodeChangeCipherSpecLayer(data []byte, p gopacket.PacketBuilder) error {
+	ccs := &ChangeCipherSpec{}
+	err := ccs.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(ccs)
+
+	return nil
 }
 
 // NewChangeCipherSpecFromRecord creates an changecipherspec from a TLS Record
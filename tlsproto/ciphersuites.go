@@ -7,6 +7,12 @@ import "fmt"
 // CipherSuite is a ciphersuite in format defined by rfc
 type CipherSuite uint16
 
+// Signaling cipher suite values
+const (
+	CipherSuiteEmptyRenegotiationInfoSCSV CipherSuite = 0x00FF
+	CipherSuiteFallbackSCSV               CipherSuite = 0x5600
+)
+
 // cipherSuiteReg stores a map with desc and if ciphersuite is secure
 var cipherSuiteReg = map[CipherSuite]struct {
 	desc   string
@@ -0,0 +1,35 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+// DecodeOptions configures how handshake messages are decoded, so decoders
+// with different needs can run in the same process
+type DecodeOptions struct {
+	// DecodeExtensions decodes the extensions of hello messages into ExtensionsInfo
+	DecodeExtensions bool
+	// ParseCertificates parses the certificates of certificate messages
+	ParseCertificates bool
+	// LazyCertificates keeps raw certificates and fingerprints, they are parsed
+	// on demand with CertificateData.Parse. Input buffer must not be reused
+	// while data is alive unless CopyBuffers is set.
+	LazyCertificates bool
+	// CertificateCache caches parsed certificates by fingerprint, nil disables it
+	CertificateCache *CertificateCache
+	// CopyBuffers copies the payload before decoding, so decoded data doesn't alias it
+	CopyBuffers bool
+	// MaxHandshakeSize is the maximum length allowed of a handshake message, zero is no limit
+	MaxHandshakeSize uint32
+	// MaxExtensions is the maximum number of extensions allowed in a hello, zero is no limit
+	MaxExtensions int
+	// Lenient returns the data decoded until an error is found instead of discarding it
+	Lenient bool
+}
+
+// DefaultDecodeOptions returns the options used by NewHandshakeFromBytes and NewHandshakesFromRecord
+func DefaultDecodeOptions() DecodeOptions {
+	return DecodeOptions{
+		DecodeExtensions:  DecodeExtensions,
+		ParseCertificates: true,
+		CertificateCache:  defaultCertCache,
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/luisguillenc/tlslayer"
+)
+
+// DTLSHandshakeHeaderLen is the length of the header of dtls handshake messages
+const DTLSHandshakeHeaderLen = 12
+
+// Limits of a reassembler created with NewDTLSReassembler
+const (
+	// DefaultDTLSMaxHandshakeSize is used if options don't limit the size of
+	// messages, because memory of a message is allocated with its first fragment
+	DefaultDTLSMaxHandshakeSize = 1 << 17
+	// DefaultDTLSMaxPending is the maximum number of messages being reassembled
+	DefaultDTLSMaxPending = 16
+)
+
+// DTLSHandshakeHeader is the header of a dtls handshake message (rfc6347), it
+// adds the sequence of the message and the fragment to the tls header
+type DTLSHandshakeHeader struct {
+	Type           HandshakeType `json:"type"`
+	Len            uint32        `json:"len"`
+	MessageSeq     uint16        `json:"messageSeq"`
+	FragmentOffset uint32        `json:"fragmentOffset"`
+	FragmentLen    uint32        `json:"fragmentLen"`
+}
+
+func (h DTLSHandshakeHeader) String() string {
+	return fmt.Sprintf("%s seq=%d fragment=%d-%d (len=%d)", h.Type, h.MessageSeq, h.FragmentOffset, h.FragmentOffset+h.FragmentLen, h.Len)
+}
+
+// IsFragment returns true if header doesn't contain the whole message
+func (h DTLSHandshakeHeader) IsFragment() bool {
+	return h.FragmentOffset != 0 || h.FragmentLen != h.Len
+}
+
+// ReadDTLSHandshakeHeader returns the header of a dtls handshake message
+func ReadDTLSHandshakeHeader(bytes []byte) (DTLSHandshakeHeader, error) {
+	if len(bytes) < DTLSHandshakeHeaderLen {
+		return DTLSHandshakeHeader{}, ErrHandshakeWrongSize
+	}
+	htype := HandshakeType(bytes[0])
+	if !htype.IsValid() {
+		return DTLSHandshakeHeader{}, ErrHandshakeWrongType
+	}
+	h := DTLSHandshakeHeader{
+		Type:           htype,
+		Len:            uint32(bytes[1])<<16 | uint32(bytes[2])<<8 | uint32(bytes[3]),
+		MessageSeq:     uint16(bytes[4])<<8 | uint16(bytes[5]),
+		FragmentOffset: uint32(bytes[6])<<16 | uint32(bytes[7])<<8 | uint32(bytes[8]),
+		FragmentLen:    uint32(bytes[9])<<16 | uint32(bytes[10])<<8 | uint32(bytes[11]),
+	}
+	if h.FragmentOffset+h.FragmentLen > h.Len {
+		return DTLSHandshakeHeader{}, ErrHandshakeBadFragment
+	}
+	return h, nil
+}
+
+// nextDTLSFragment reads the header and returns the body of the first fragment of data
+func nextDTLSFragment(data []byte) (DTLSHandshakeHeader, []byte, error) {
+	h, err := ReadDTLSHandshakeHeader(data)
+	if err != nil {
+		return h, nil, err
+	}
+	if len(data)-DTLSHandshakeHeaderLen < int(h.FragmentLen) {
+		return h, nil, ErrHandshakeBadLength
+	}
+	return h, data[DTLSHandshakeHeaderLen : DTLSHandshakeHeaderLen+int(h.FragmentLen)], nil
+}
+
+// newTLSMessage returns a buffer for the message of the header with the tls header
+func newTLSMessage(h DTLSHandshakeHeader) []byte {
+	msg := make([]byte, 4+int(h.Len))
+	msg[0] = byte(h.Type)
+	msg[1] = byte(h.Len >> 16)
+	msg[2] = byte(h.Len >> 8)
+	msg[3] = byte(h.Len)
+	return msg
+}
+
+// dtlsRange is a range [start, end) of a message received
+type dtlsRange struct {
+	start, end uint32
+}
+
+// dtlsMessage is a message being reassembled, msg has tls format
+type dtlsMessage struct {
+	htype  HandshakeType
+	msg    []byte
+	ranges []dtlsRange
+}
+
+// add copies the fragment and merges its range with the received ones
+func (m *dtlsMessage) add(offset uint32, fragment []byte) {
+	copy(m.msg[4+offset:], fragment)
+	start, end := offset, offset+uint32(len(fragment))
+	merged := m.ranges[:0]
+	for _, r := range m.ranges {
+		if r.end < start || r.start > end {
+			merged = append(merged, r)
+			continue
+		}
+		if r.start < start {
+			start = r.start
+		}
+		if r.end > end {
+			end = r.end
+		}
+	}
+	m.ranges = append(merged, dtlsRange{start: start, end: end})
+}
+
+// completed returns true if all the bytes of the message were received
+func (m *dtlsMessage) completed() bool {
+	mlen := uint32(len(m.msg) - 4)
+	return len(m.ranges) == 1 && m.ranges[0].start == 0 && m.ranges[0].end == mlen
+}
+
+// DTLSReassembler reassembles the handshake messages of a direction of a dtls
+// flow. Fragments may arrive out of order or retransmitted, messages are
+// returned once when they are completed. It isn't safe for concurrent use.
+type DTLSReassembler struct {
+	Options DecodeOptions
+	// MaxPending is the maximum number of messages being reassembled, when
+	// it's reached the pending messages are discarded. Zero means no limit.
+	MaxPending int
+
+	pending map[uint16]*dtlsMessage
+	done    map[uint16]bool
+}
+
+// NewDTLSReassembler returns a reassembler that decodes messages using the
+// options, if they don't limit the size of messages DefaultDTLSMaxHandshakeSize is used
+func NewDTLSReassembler(opts DecodeOptions) *DTLSReassembler {
+	if opts.MaxHandshakeSize == 0 {
+		opts.MaxHandshakeSize = DefaultDTLSMaxHandshakeSize
+	}
+	return &DTLSReassembler{
+		Options:    opts,
+		MaxPending: DefaultDTLSMaxPending,
+		pending:    make(map[uint16]*dtlsMessage),
+		done:       make(map[uint16]bool),
+	}
+}
+
+// Reset discards the messages being reassembled and the sequence numbers completed
+func (r *DTLSReassembler) Reset() {
+	r.pending = make(map[uint16]*dtlsMessage)
+	r.done = make(map[uint16]bool)
+}
+
+// Pending returns the number of messages being reassembled
+func (r *DTLSReassembler) Pending() int {
+	return len(r.pending)
+}
+
+// AddRecord adds the fragments of a dtls handshake record and returns the
+// messages completed. Records of epochs greater than zero are encrypted and
+// they are ignored.
+func (r *DTLSReassembler) AddRecord(d *tlslayer.DTLSRecord) ([]*Handshake, error) {
+	if d.Type != tlslayer.ContentTypeHandshake {
+		return nil, ErrUnexpectedRecordType
+	}
+	if d.Epoch > 0 {
+		return nil, nil
+	}
+	return r.AddFragments(d.Payload())
+}
+
+// AddFragments adds the fragments of the byte slice and returns the messages
+// completed. Messages decoded before an error are returned with it, in lenient
+// mode the messages are decoded and the first error is returned.
+func (r *DTLSReassembler) AddFragments(data []byte) ([]*Handshake, error) {
+	var handshakes []*Handshake
+	var firstErr error
+	for len(data) > 0 {
+		h, fragment, err := nextDTLSFragment(data)
+		if err != nil {
+			if firstErr != nil {
+				return handshakes, firstErr
+			}
+			return handshakes, err
+		}
+		data = data[DTLSHandshakeHeaderLen+len(fragment):]
+
+		msg, err := r.add(h, fragment)
+		if err != nil {
+			return handshakes, err
+		}
+		if msg == nil {
+			continue
+		}
+		handshake, err := newHandshakeFromBytes(msg, &r.Options)
+		if err != nil {
+			if !r.Options.Lenient || handshake == nil {
+				return handshakes, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		handshake.MessageSeq = h.MessageSeq
+		handshakes = append(handshakes, handshake)
+	}
+	return handshakes, firstErr
+}
+
+// add adds a fragment and returns the message in tls format if it's completed
+func (r *DTLSReassembler) add(h DTLSHandshakeHeader, fragment []byte) ([]byte, error) {
+	if r.pending == nil {
+		r.Reset()
+	}
+	if r.done[h.MessageSeq] {
+		// retransmission
+		return nil, nil
+	}
+	if r.Options.MaxHandshakeSize > 0 && h.Len > r.Options.MaxHandshakeSize {
+		return nil, ErrHandshakeTooLarge
+	}
+	m, ok := r.pending[h.MessageSeq]
+	if !ok {
+		if r.MaxPending > 0 && len(r.pending) >= r.MaxPending {
+			r.pending = make(map[uint16]*dtlsMessage)
+		}
+		m = &dtlsMessage{htype: h.Type, msg: newTLSMessage(h)}
+		r.pending[h.MessageSeq] = m
+	} else if m.htype != h.Type || uint32(len(m.msg)-4) != h.Len {
+		return nil, ErrHandshakeFragMissmatch
+	}
+	m.add(h.FragmentOffset, fragment)
+	if !m.completed() {
+		return nil, nil
+	}
+	delete(r.pending, h.MessageSeq)
+	r.done[h.MessageSeq] = true
+	return m.msg, nil
+}
+
+// DTLSHandshakeLayer is the layer of dtls handshake records. It decodes the
+// messages that aren't fragmented, fragments have to be reassembled with a
+// DTLSReassembler.
+type DTLSHandshakeLayer struct {
+	Options DecodeOptions
+	// Headers are the headers of all the fragments of the record
+	Headers []DTLSHandshakeHeader
+	// Handshakes are the messages decoded from the record
+	Handshakes []*Handshake
+
+	contents []byte
+}
+
+// DecodeFromBytes decodes the fragments of the byte slice.
+// In lenient mode the messages are decoded and the first error is returned.
+func (d *DTLSHandshakeLayer) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	d.Headers = d.Headers[:0]
+	d.Handshakes = d.Handshakes[:0]
+	d.contents = data
+
+	var firstErr error
+	for len(data) > 0 {
+		h, fragment, err := nextDTLSFragment(data)
+		if err != nil {
+			if err == ErrHandshakeBadLength && df != nil {
+				df.SetTruncated()
+			}
+			if firstErr != nil {
+				return firstErr
+			}
+			return err
+		}
+		data = data[DTLSHandshakeHeaderLen+len(fragment):]
+		d.Headers = append(d.Headers, h)
+		if h.IsFragment() {
+			continue
+		}
+
+		msg := newTLSMessage(h)
+		copy(msg[4:], fragment)
+		handshake, err := newHandshakeFromBytes(msg, &d.Options)
+		if err != nil {
+			if !d.Options.Lenient || handshake == nil {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		handshake.MessageSeq = h.MessageSeq
+		d.Handshakes = append(d.Handshakes, handshake)
+	}
+	return firstErr
+}
+
+// CanDecode satisfaces the interface
+func (d *DTLSHandshakeLayer) CanDecode() gopacket.LayerClass {
+	return LayerTypeDTLSHandshake
+}
+
+// LayerType satisfaces the interface
+func (d *DTLSHandshakeLayer) LayerType() gopacket.LayerType {
+	return LayerTypeDTLSHandshake
+}
+
+// NextLayerType satisfaces the interface, handshake is the last layer
+func (d *DTLSHandshakeLayer) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+// LayerContents satisfaces the interface
+func (d *DTLSHandshakeLayer) LayerContents() []byte {
+	return d.contents
+}
+
+// LayerPayload satisfaces the interface, handshake messages have no payload
+func (d *DTLSHandshakeLayer) LayerPayload() []byte {
+	return nil
+}
+
+// decodeDTLSHandshakeLayer decodes the byte slice and add dtls handshake layer to packet builder
+func decodeDTLSHandshakeLayer(data []byte, p gopacket.PacketBuilder) error {
+	d := &DTLSHandshakeLayer{Options: DefaultDecodeOptions()}
+	err := d.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(d)
+
+	return nil
+}
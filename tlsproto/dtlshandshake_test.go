@@ -0,0 +1,173 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlsproto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/luisguillenc/tlslayer"
+)
+
+// body of a dtls 1.2 clienthello with a cookie of 4 bytes
+var testDTLSClientHelloBody = []byte{
+	0xfe, 0xfd,
+	0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	0x00,                         // session id
+	0x04, 0xde, 0xad, 0xbe, 0xef, // cookie
+	0x00, 0x04, 0xc0, 0x2b, 0xc0, 0x2f, // cipher suites
+	0x01, 0x00, // compression methods
+	0x00, 0x0e,
+	0x00, 0x0a, 0x00, 0x04, 0x00, 0x02, 0x00, 0x17, // supported groups
+	0x00, 0x0b, 0x00, 0x02, 0x01, 0x00, // ec point formats
+}
+
+// testDTLSFragment returns a dtls handshake fragment of the message body
+func testDTLSFragment(htype HandshakeType, seq uint16, body []byte, offset, length int) []byte {
+	mlen, flen := len(body), length
+	frag := []byte{
+		byte(htype), byte(mlen >> 16), byte(mlen >> 8), byte(mlen),
+		byte(seq >> 8), byte(seq),
+		byte(offset >> 16), byte(offset >> 8), byte(offset),
+		byte(flen >> 16), byte(flen >> 8), byte(flen),
+	}
+	return append(frag, body[offset:offset+length]...)
+}
+
+func TestDecodeHelloVerifyRequest(t *testing.T) {
+	payload := []byte{0x03, 0x00, 0x00, 0x07, 0xfe, 0xff, 0x04, 0x01, 0x02, 0x03, 0x04}
+	handshake, err := NewHandshakeFromBytes(payload)
+	if err != nil {
+		t.Fatal("getting handshake from bytes:", err)
+	}
+	if handshake.Type != HandshakeTypeHelloVerifyRequest {
+		t.Errorf("expected handshake type: %v, got: %v", HandshakeTypeHelloVerifyRequest, handshake.Type)
+	}
+	hvr := handshake.HelloVerifyRequest
+	if hvr == nil {
+		t.Fatal("HelloVerifyRequest doesn't decoded")
+	}
+	if hvr.ServerVersion != tlslayer.VersionDTLS10 || !bytes.Equal(hvr.Cookie, []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("unexpected hello verify request: %v", hvr)
+	}
+
+	_, err = NewHandshakeFromBytes([]byte{0x03, 0x00, 0x00, 0x04, 0xfe, 0xff, 0x04, 0x01})
+	if err != ErrHandshakeBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeBadLength, err)
+	}
+}
+
+func TestDTLSHandshakeLayer(t *testing.T) {
+	body := testDTLSClientHelloBody
+	data := testDTLSFragment(HandshakeTypeClientHello, 1, body, 0, len(body))
+	// a fragment of other message in the same record
+	data = append(data, testDTLSFragment(HandshakeTypeCertificate, 2, make([]byte, 100), 0, 50)...)
+
+	d := &DTLSHandshakeLayer{Options: DefaultDecodeOptions()}
+	if err := d.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("decoding dtls handshake:", err)
+	}
+	if len(d.Headers) != 2 || len(d.Handshakes) != 1 {
+		t.Fatalf("expected headers: 2 and handshakes: 1, got: %v %v", len(d.Headers), len(d.Handshakes))
+	}
+	if !d.Headers[1].IsFragment() || d.Headers[1].FragmentLen != 50 || d.Headers[1].Len != 100 {
+		t.Errorf("unexpected fragment header: %v", d.Headers[1])
+	}
+	hsk := d.Handshakes[0]
+	if hsk.MessageSeq != 1 || hsk.ClientHello == nil {
+		t.Fatalf("expected clienthello with seq: 1, got: %v", hsk)
+	}
+	ch := hsk.ClientHello
+	if ch.ClientVersion != tlslayer.VersionDTLS12 {
+		t.Errorf("expected version: %v, got: %v", tlslayer.VersionDTLS12, ch.ClientVersion)
+	}
+	if !bytes.Equal(ch.Cookie, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("unexpected cookie: %x", ch.Cookie)
+	}
+	if len(ch.CipherSuites) != 2 || len(ch.Extensions) != 2 {
+		t.Errorf("unexpected clienthello: %v", ch)
+	}
+
+	_, err := ReadDTLSHandshakeHeader([]byte{0x01, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x08})
+	if err != ErrHandshakeBadFragment {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeBadFragment, err)
+	}
+}
+
+func TestDTLSReassembler(t *testing.T) {
+	RegisterLayers()
+	body := testDTLSClientHelloBody
+	frag1 := testDTLSFragment(HandshakeTypeClientHello, 0, body, 0, 20)
+	frag2 := testDTLSFragment(HandshakeTypeClientHello, 0, body, 20, 30)
+	frag3 := testDTLSFragment(HandshakeTypeClientHello, 0, body, 40, len(body)-40)
+
+	r := NewDTLSReassembler(DefaultDecodeOptions())
+	// out of order and overlapped
+	hsks, err := r.AddFragments(append(append([]byte{}, frag3...), frag1...))
+	if err != nil || len(hsks) != 0 {
+		t.Fatalf("unexpected handshakes: %v, error: %v", hsks, err)
+	}
+	if r.Pending() != 1 {
+		t.Errorf("expected pending: 1, got: %v", r.Pending())
+	}
+	hsks, err = r.AddFragments(frag2)
+	if err != nil || len(hsks) != 1 {
+		t.Fatalf("expected handshakes: 1, got: %v, error: %v", len(hsks), err)
+	}
+	if hsks[0].ClientHello == nil || !bytes.Equal(hsks[0].ClientHello.Cookie, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("unexpected handshake: %v", hsks[0])
+	}
+	if r.Pending() != 0 {
+		t.Errorf("expected pending: 0, got: %v", r.Pending())
+	}
+	// retransmission
+	hsks, err = r.AddFragments(testDTLSFragment(HandshakeTypeClientHello, 0, body, 0, len(body)))
+	if err != nil || len(hsks) != 0 {
+		t.Errorf("unexpected retransmitted handshakes: %v, error: %v", hsks, err)
+	}
+
+	// record of epoch 0
+	data := testDTLSFragment(HandshakeTypeServerHelloDone, 1, nil, 0, 0)
+	record := append([]byte{0x16, 0xfe, 0xfd, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, byte(len(data))}, data...)
+	dtlsr := &tlslayer.DTLSRecord{}
+	if err := dtlsr.DecodeFromBytes(record, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("decoding dtls record:", err)
+	}
+	if dtlsr.NextLayerType() != LayerTypeDTLSHandshake {
+		t.Errorf("expected next layer: %v, got: %v", LayerTypeDTLSHandshake, dtlsr.NextLayerType())
+	}
+	hsks, err = r.AddRecord(dtlsr)
+	if err != nil || len(hsks) != 1 || hsks[0].Type != HandshakeTypeServerHelloDone || hsks[0].MessageSeq != 1 {
+		t.Errorf("expected server_hello_done, got: %v, error: %v", hsks, err)
+	}
+	// all the records of the datagram are decoded with their messages
+	alert := []byte{0x15, 0xfe, 0xfd, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x02, 0x01, 0x00}
+	packet := gopacket.NewPacket(append(record, alert...), tlslayer.LayerTypeDTLSRecord, gopacket.Default)
+	if packet.ErrorLayer() != nil {
+		t.Fatal("decoding packet:", packet.ErrorLayer().Error())
+	}
+	want := []gopacket.LayerType{tlslayer.LayerTypeDTLSRecord, LayerTypeDTLSHandshake, tlslayer.LayerTypeDTLSRecord, LayerTypeAlert}
+	if got := packet.Layers(); len(got) != len(want) {
+		t.Errorf("expected layers: %v, got: %v", want, got)
+	} else {
+		for i := range want {
+			if got[i].LayerType() != want[i] {
+				t.Errorf("expected layer: %v, got: %v", want[i], got[i].LayerType())
+			}
+		}
+	}
+
+	// errors
+	r.Reset()
+	r.AddFragments(frag1)
+	_, err = r.AddFragments(testDTLSFragment(HandshakeTypeCertificate, 0, body, 20, 10))
+	if err != ErrHandshakeFragMissmatch {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeFragMissmatch, err)
+	}
+	r.Options.MaxHandshakeSize = 32
+	_, err = r.AddFragments(testDTLSFragment(HandshakeTypeClientHello, 1, body, 0, 10))
+	if err != ErrHandshakeTooLarge {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeTooLarge, err)
+	}
+}
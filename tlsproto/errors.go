@@ -21,14 +21,25 @@ var (
 	ErrCCSInvalidValue = errors.New("unexpected change_cipher_spec value")
 )
 
+// common errors in heartbeat
+var (
+	ErrHeartbeatInvalidType = errors.New("unexpected heartbeat message type or is ciphered")
+	ErrHeartbeatBadLength   = errors.New("heartbeat payload length greater than message")
+)
+
 // common errors in handshake
 var (
 	ErrHandshakeWrongSize        = errors.New("handshake is of wrong size")
 	ErrHandshakeWrongType        = errors.New("handshake is of wrong type")
 	ErrHandshakeBadLength        = errors.New("handshake has a malformed length")
 	ErrHandshakeExtBadLength     = errors.New("handshake extension has a malformed length")
+	ErrHandshakeExtBadValue      = errors.New("handshake extension has an invalid value")
 	ErrHandshakePayloadMissmatch = errors.New("handshake payload missmatch")
 	ErrHandshakeFragmented       = errors.New("handshake is fragmented in more than one tls record")
+	ErrHandshakeTooLarge         = errors.New("handshake exceeds the maximum size allowed")
+	ErrHandshakeExtTooMany       = errors.New("handshake exceeds the maximum number of extensions allowed")
+	ErrHandshakeBadFragment      = errors.New("dtls handshake fragment is out of the message bounds")
+	ErrHandshakeFragMissmatch    = errors.New("dtls handshake fragment doesn't match previous fragments")
 )
 
 // common errors in certificates
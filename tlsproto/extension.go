@@ -3,6 +3,7 @@
 package tlsproto
 
 import (
+	"crypto/x509/pkix"
 	"fmt"
 )
 
@@ -10,7 +11,46 @@ import (
 type Extension struct {
 	Type    ExtensionType `json:"type"`
 	Len     uint16        `json:"len"`
-	payload []byte
+	Payload []byte        `json:"payload,omitempty"`
+
+	// Status is the result of decoding the payload, Err and Error are the
+	// error and its message if it's malformed
+	Status ExtensionStatus `json:"status"`
+	Err    error           `json:"-"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ExtensionStatus is the result of decoding an extension
+type ExtensionStatus uint8
+
+// ExtensionStatus possible values
+const (
+	ExtStatusNotDecoded ExtensionStatus = 0
+	ExtStatusOK         ExtensionStatus = 1
+	ExtStatusMalformed  ExtensionStatus = 2
+	ExtStatusUnknown    ExtensionStatus = 3
+	ExtStatusOpaque     ExtensionStatus = 4
+)
+
+func (s ExtensionStatus) getDesc() string {
+	switch s {
+	case ExtStatusNotDecoded:
+		return "not_decoded"
+	case ExtStatusOK:
+		return "ok"
+	case ExtStatusMalformed:
+		return "malformed"
+	case ExtStatusUnknown:
+		return "unknown"
+	case ExtStatusOpaque:
+		return "opaque"
+	default:
+		return "invalid"
+	}
+}
+
+func (s ExtensionStatus) String() string {
+	return fmt.Sprintf("%s(%d)", s.getDesc(), s)
 }
 
 // ExtensionsInfo stores all decoded information from extensions
@@ -26,13 +66,84 @@ type ExtensionsInfo struct {
 	// ExtECPointFormats
 	ECPointFormats []ECPointFormat `json:"ecPointFormats,omitempty"`
 	// ExtStatusRequest
-	OSCP bool `json:"oscp"`
+	// OSCP is true if client requested an ocsp status, StatusRequestAck if
+	// server acknowledged that it will send the status
+	OSCP             bool                      `json:"oscp"`
+	StatusRequest    *CertificateStatusRequest `json:"statusRequest,omitempty"`
+	StatusRequestAck bool                      `json:"statusRequestAck"`
+	// ExtStatusRequestV2
+	StatusRequestV2  bool                       `json:"statusRequestV2"`
+	StatusRequestsV2 []CertificateStatusRequest `json:"statusRequestsV2,omitempty"`
 	// ExtALPN
 	ALPNs []string `json:"alpns,omitempty"`
 	// ExtKeyShare
 	KeyShareEntries []KeyShareEntry `json:"keyShareEntries,omitempty"`
 	// ExtPSKKeyExchangeModes
 	PSKKeyExchangeModes []PSKKeyExchangeMode `json:"pskKeyExchangeModes,omitempty"`
+	// ExtPreSharedKey
+	PreSharedKey        bool          `json:"preSharedKey"`
+	PSKIdentities       []PSKIdentity `json:"pskIdentities,omitempty"`
+	PSKBinders          [][]byte      `json:"pskBinders,omitempty"`
+	PSKSelectedIdentity uint16        `json:"pskSelectedIdentity"`
+	// ExtEarlyData
+	EarlyData        bool   `json:"earlyData"`
+	MaxEarlyDataSize uint32 `json:"maxEarlyDataSize,omitempty"`
+	// ExtQUICTransportParams
+	QUICTransportParams []QUICTransportParam `json:"quicTransportParams,omitempty"`
+	// ExtRenegotiationInfo
+	RenegotiationInfo      bool   `json:"renegotiationInfo"`
+	RenegotiatedConnection []byte `json:"renegotiatedConnection,omitempty"`
+	// ExtExtendedMasterSecret
+	ExtendedMasterSecret bool `json:"extendedMasterSecret"`
+	// ExtEncryptThenMAC
+	EncryptThenMAC bool `json:"encryptThenMAC"`
+	// ExtSessionTicket
+	SessionTicket     bool   `json:"sessionTicket"`
+	SessionTicketLen  uint16 `json:"sessionTicketLen"`
+	SessionTicketData []byte `json:"sessionTicketData,omitempty"`
+	// ExtCookie
+	Cookie []byte `json:"cookie,omitempty"`
+	// ExtPadding
+	PaddingLen     uint16 `json:"paddingLen"`
+	PaddingNonZero bool   `json:"paddingNonZero"`
+	// ExtMaxFragLen
+	MaxFragmentLength MaxFragmentLength `json:"maxFragmentLength,omitempty"`
+	// ExtRecordSizeLimit
+	RecordSizeLimit uint16 `json:"recordSizeLimit,omitempty"`
+	// ExtCertAuthorities
+	CertificateAuthorities []pkix.RDNSequence `json:"certificateAuthorities,omitempty"`
+	// ExtOIDFilters
+	OIDFilters []OIDFilter `json:"oidFilters,omitempty"`
+	// ExtSignatureAlgsCert
+	SignatureSchemesCert []SignatureScheme `json:"signatureSchemesCert,omitempty"`
+	// ExtApplicationSettings
+	ALPSProtocols []string `json:"alpsProtocols,omitempty"`
+	// ExtDelegatedCredentials
+	DelegatedCredentialSchemes []SignatureScheme `json:"delegatedCredentialSchemes,omitempty"`
+	// ExtTokenBinding
+	TokenBindingVersion   uint16                     `json:"tokenBindingVersion,omitempty"`
+	TokenBindingKeyParams []TokenBindingKeyParameter `json:"tokenBindingKeyParams,omitempty"`
+	// ExtUseSRTP
+	SRTPProtectionProfiles []SRTPProtectionProfile `json:"srtpProtectionProfiles,omitempty"`
+	SRTPMKI                []byte                  `json:"srtpMKI,omitempty"`
+	// ExtHeartbeat
+	HeartbeatMode HeartbeatMode `json:"heartbeatMode,omitempty"`
+	// ExtClientCertType
+	ClientCertTypes []CertificateType `json:"clientCertTypes,omitempty"`
+	// ExtServerCertType
+	ServerCertTypes []CertificateType `json:"serverCertTypes,omitempty"`
+	// ExtNPN
+	NPN          bool     `json:"npn"`
+	NPNProtocols []string `json:"npnProtocols,omitempty"`
+	// ExtTrustedCAKeys
+	TrustedCAKeys []TrustedAuthority `json:"trustedCAKeys,omitempty"`
+	// ExtClientCertURL
+	ClientCertURL bool `json:"clientCertURL"`
+	// ExtTruncatedHMAC
+	TruncatedHMAC bool `json:"truncatedHMAC"`
+
+	// statusRequest is the memory of StatusRequest reused between decodes
+	statusRequest *CertificateStatusRequest
 }
 
 // ExtensionType is an extension type defined by rfc
@@ -71,6 +182,7 @@ const (
 	ExtPwdProtect           ExtensionType = 29
 	ExtPwdClear             ExtensionType = 30
 	ExtPasswordSalt         ExtensionType = 31
+	ExtDelegatedCredentials ExtensionType = 34
 	ExtSessionTicket        ExtensionType = 35
 	ExtPreSharedKey         ExtensionType = 41
 	ExtEarlyData            ExtensionType = 42
@@ -82,8 +194,11 @@ const (
 	ExtPostHandshakeAuth    ExtensionType = 49
 	ExtSignatureAlgsCert    ExtensionType = 50
 	ExtKeyShare             ExtensionType = 51
+	ExtQUICTransportParams  ExtensionType = 57
 	ExtNPN                  ExtensionType = 13172 // Next Protocol Negotiation not ratified and replaced by ALPN
+	ExtApplicationSettings  ExtensionType = 17513 // ALPS draft used by Chrome
 	ExtRenegotiationInfo    ExtensionType = 65281
+	ExtQUICTransportDraft   ExtensionType = 65445 // Used by quic drafts before rfc9000
 )
 
 // decodeExt is a function that puts decoded data from an extension into an info struct
@@ -94,10 +209,10 @@ var extensionReg = map[ExtensionType]struct {
 	decoder decodeExt
 }{
 	ExtServerName:           {"server_name", decodeExtServerName},
-	ExtMaxFragLen:           {"max_fragment_length", nil},
-	ExtClientCertURL:        {"client_certificate_url", nil},
-	ExtTrustedCAKeys:        {"trusted_ca_keys", nil},
-	ExtTruncatedHMAC:        {"truncated_hmac", nil},
+	ExtMaxFragLen:           {"max_fragment_length", decodeExtMaxFragLen},
+	ExtClientCertURL:        {"client_certificate_url", decodeExtClientCertURL},
+	ExtTrustedCAKeys:        {"trusted_ca_keys", decodeExtTrustedCAKeys},
+	ExtTruncatedHMAC:        {"truncated_hmac", decodeExtTruncatedHMAC},
 	ExtStatusRequest:        {"status_request", decodeExtStatusRequest},
 	ExtUserMapping:          {"user_mapping", nil},
 	ExtClientAuthz:          {"client_authz", nil},
@@ -107,36 +222,40 @@ var extensionReg = map[ExtensionType]struct {
 	ExtECPointFormats:       {"ec_point_formats", decodeExtECPointFormats},
 	ExtSRP:                  {"srp", nil},
 	ExtSignatureAlgs:        {"signature_algorithms", decodeExtSignatureAlgs},
-	ExtUseSRTP:              {"use_srtp", nil},
-	ExtHeartbeat:            {"heartbeat", nil},
+	ExtUseSRTP:              {"use_srtp", decodeExtUseSRTP},
+	ExtHeartbeat:            {"heartbeat", decodeExtHeartbeat},
 	ExtALPN:                 {"application_layer_protocol_negotiation", decodeExtALPN},
-	ExtStatusRequestV2:      {"status_request_v2", nil},
+	ExtStatusRequestV2:      {"status_request_v2", decodeExtStatusRequestV2},
 	ExtSignedCertTS:         {"signed_certificate_timestamp", nil},
-	ExtClientCertType:       {"client_certificate_type", nil},
-	ExtServerCertType:       {"server_certificate_type", nil},
-	ExtPadding:              {"padding", nil},
-	ExtEncryptThenMAC:       {"encrypt_then_mac", nil},
-	ExtExtendedMasterSecret: {"extended_master_secret", nil},
-	ExtTokenBinding:         {"token_binding", nil},
+	ExtClientCertType:       {"client_certificate_type", decodeExtClientCertType},
+	ExtServerCertType:       {"server_certificate_type", decodeExtServerCertType},
+	ExtPadding:              {"padding", decodeExtPadding},
+	ExtEncryptThenMAC:       {"encrypt_then_mac", decodeExtEncryptThenMAC},
+	ExtExtendedMasterSecret: {"extended_master_secret", decodeExtExtendedMasterSecret},
+	ExtTokenBinding:         {"token_binding", decodeExtTokenBinding},
 	ExtCachedInfo:           {"cached_info", nil},
 	ExtCompressCert:         {"compress_certificate ", nil},
-	ExtRecordSizeLimit:      {"record_size_limit", nil},
+	ExtRecordSizeLimit:      {"record_size_limit", decodeExtRecordSizeLimit},
 	ExtPwdProtect:           {"pwd_protect", nil},
 	ExtPwdClear:             {"pwd_clear", nil},
 	ExtPasswordSalt:         {"password_salt", nil},
-	ExtSessionTicket:        {"session_ticket", nil},
-	ExtPreSharedKey:         {"pre_shared_key", nil},
-	ExtEarlyData:            {"early_data", nil},
+	ExtDelegatedCredentials: {"delegated_credentials", decodeExtDelegatedCredentials},
+	ExtSessionTicket:        {"session_ticket", decodeExtSessionTicket},
+	ExtPreSharedKey:         {"pre_shared_key", decodeExtPreSharedKey},
+	ExtEarlyData:            {"early_data", decodeExtEarlyData},
 	ExtSupportedVersions:    {"supported_versions", decodeExtSupportedVersions},
-	ExtCookie:               {"cookie", nil},
+	ExtCookie:               {"cookie", decodeExtCookie},
 	ExtPSKKeyExchangeModes:  {"psk_key_exchange_modes", decodeExtPSKKeyExchangeModes},
-	ExtCertAuthorities:      {"certificate_authorities", nil},
-	ExtOIDFilters:           {"oid_filters", nil},
+	ExtCertAuthorities:      {"certificate_authorities", decodeExtCertAuthorities},
+	ExtOIDFilters:           {"oid_filters", decodeExtOIDFilters},
 	ExtPostHandshakeAuth:    {"post_handshake_auth", nil},
-	ExtSignatureAlgsCert:    {"signature_algorithms_cert", nil},
+	ExtSignatureAlgsCert:    {"signature_algorithms_cert", decodeExtSignatureAlgsCert},
 	ExtKeyShare:             {"key_share", decodeExtKeyShare},
-	ExtNPN:                  {"next_protocol_negotiation", nil},
-	ExtRenegotiationInfo:    {"renegotiation_info", nil},
+	ExtQUICTransportParams:  {"quic_transport_parameters", decodeExtQUICTransportParams},
+	ExtNPN:                  {"next_protocol_negotiation", decodeExtNPN},
+	ExtApplicationSettings:  {"application_settings", decodeExtApplicationSettings},
+	ExtRenegotiationInfo:    {"renegotiation_info", decodeExtRenegotiationInfo},
+	ExtQUICTransportDraft:   {"quic_transport_parameters_draft", decodeExtQUICTransportParams},
 }
 
 func (e ExtensionType) getDesc() string {
@@ -168,35 +287,64 @@ func (i *ExtensionsInfo) String() string {
 	str += fmt.Sprintf("Signature Schemes: %v\n", i.SignatureSchemes)
 	str += fmt.Sprintf("Supported Groups: %v\n", i.SupportedGroups)
 	str += fmt.Sprintf("ECPoints Formats: %v\n", i.ECPointFormats)
-	str += fmt.Sprintf("OSCP: %v\n", i.OSCP)
+	str += fmt.Sprintf("OSCP: %v %v ack=%v\n", i.OSCP, i.StatusRequest, i.StatusRequestAck)
+	str += fmt.Sprintf("Status Request V2: %v %v\n", i.StatusRequestV2, i.StatusRequestsV2)
 	str += fmt.Sprintf("ALPNs: %v", i.ALPNs)
 	str += fmt.Sprintf("Supported Versions: %v\n", i.SupportedVersions)
 	str += fmt.Sprintf("Key Share Entries: %v\n", i.KeyShareEntries)
 	str += fmt.Sprintf("PSK Key Exchange Modes: %v\n", i.PSKKeyExchangeModes)
+	str += fmt.Sprintf("Pre Shared Key: %v %v selected=%d\n", i.PreSharedKey, i.PSKIdentities, i.PSKSelectedIdentity)
+	str += fmt.Sprintf("Early Data: %v (max=%d)\n", i.EarlyData, i.MaxEarlyDataSize)
+	str += fmt.Sprintf("QUIC Transport Parameters: %v\n", i.QUICTransportParams)
+	str += fmt.Sprintf("Renegotiation Info: %v %#v\n", i.RenegotiationInfo, i.RenegotiatedConnection)
+	str += fmt.Sprintf("Extended Master Secret: %v\n", i.ExtendedMasterSecret)
+	str += fmt.Sprintf("Encrypt Then MAC: %v\n", i.EncryptThenMAC)
+	str += fmt.Sprintf("Session Ticket: %v (len=%d)\n", i.SessionTicket, i.SessionTicketLen)
+	str += fmt.Sprintf("Cookie: %#v\n", i.Cookie)
+	str += fmt.Sprintf("Padding: (len=%d) nonzero=%v\n", i.PaddingLen, i.PaddingNonZero)
+	str += fmt.Sprintf("Max Fragment Length: %v\n", i.MaxFragmentLength)
+	str += fmt.Sprintf("Record Size Limit: %d\n", i.RecordSizeLimit)
+	str += fmt.Sprintf("Certificate Authorities: %v\n", i.CertificateAuthorities)
+	str += fmt.Sprintf("OID Filters: %v\n", i.OIDFilters)
+	str += fmt.Sprintf("Signature Schemes Cert: %v\n", i.SignatureSchemesCert)
+	str += fmt.Sprintf("ALPS Protocols: %v\n", i.ALPSProtocols)
+	str += fmt.Sprintf("Delegated Credential Schemes: %v\n", i.DelegatedCredentialSchemes)
+	str += fmt.Sprintf("Token Binding: %#04x %v\n", i.TokenBindingVersion, i.TokenBindingKeyParams)
+	str += fmt.Sprintf("SRTP Protection Profiles: %v MKI: %#v\n", i.SRTPProtectionProfiles, i.SRTPMKI)
+	str += fmt.Sprintf("Heartbeat: %v\n", i.HeartbeatMode)
+	str += fmt.Sprintf("Client Certificate Types: %v\n", i.ClientCertTypes)
+	str += fmt.Sprintf("Server Certificate Types: %v\n", i.ServerCertTypes)
+	str += fmt.Sprintf("NPN: %v %v\n", i.NPN, i.NPNProtocols)
+	str += fmt.Sprintf("Trusted CA Keys: %v\n", i.TrustedCAKeys)
+	str += fmt.Sprintf("Client Certificate URL: %v\n", i.ClientCertURL)
+	str += fmt.Sprintf("Truncated HMAC: %v\n", i.TruncatedHMAC)
 
 	return str
 }
 
 const extensionsCap = 16
 
-// getExtensionsFromBytes returns array with extension types and payloads
-func getExtensionsFromBytes(payload []byte) ([]Extension, error) {
-	extensions := make([]Extension, 0, extensionsCap)
+// appendExtensionsFromBytes appends to the slice extension types and payloads, if max is
+// greater than zero it's the maximum number of extensions allowed
+func appendExtensionsFromBytes(extensions []Extension, payload []byte, max int) ([]Extension, error) {
 	for len(payload) > 0 {
+		if max > 0 && len(extensions) >= max {
+			return extensions, ErrHandshakeExtTooMany
+		}
 		if len(payload) < 4 {
-			return nil, ErrHandshakeExtBadLength
+			return extensions, ErrHandshakeExtBadLength
 		}
 		extType := ExtensionType(payload[0])<<8 | ExtensionType(payload[1])
 		length := uint16(payload[2])<<8 | uint16(payload[3])
 
 		// get data
 		if len(payload) < 4+int(length) {
-			return nil, ErrHandshakeExtBadLength
+			return extensions, ErrHandshakeExtBadLength
 		}
 		data := payload[4 : 4+length]
 
 		// add extension type and bytes
-		extension := Extension{Type: extType, Len: length, payload: data}
+		extension := Extension{Type: extType, Len: length, Payload: data}
 		extensions = append(extensions, extension)
 
 		// forward to next extension
@@ -205,19 +353,70 @@ func getExtensionsFromBytes(payload []byte) ([]Extension, error) {
 	return extensions, nil
 }
 
-// getExtensionsInfo process array with extensions and decodes its information into an ExtensionsInfo struct
-func getExtensionsInfo(ht HandshakeType, extensions []Extension) (*ExtensionsInfo, error) {
-	info := &ExtensionsInfo{}
-	for _, extension := range extensions {
-		if ext, ok := extensionReg[extension.Type]; ok {
-			if ext.decoder == nil {
-				continue
-			}
-			err := ext.decoder(info, ht, extension.payload)
-			if err != nil {
-				return info, err
-			}
+// decodeExtensionsInfo process array with extensions and decodes its information into an ExtensionsInfo struct,
+// the result of each decoder is stored in the extension and malformed extensions don't stop the process
+func decodeExtensionsInfo(info *ExtensionsInfo, ht HandshakeType, extensions []Extension) {
+	for i := range extensions {
+		extension := &extensions[i]
+		ext, ok := extensionReg[extension.Type]
+		if !ok {
+			extension.Status = ExtStatusUnknown
+			continue
+		}
+		if ext.decoder == nil {
+			extension.Status = ExtStatusOpaque
+			continue
+		}
+		err := ext.decoder(info, ht, extension.Payload)
+		if err != nil {
+			extension.Status = ExtStatusMalformed
+			extension.Err = err
+			extension.Error = err.Error()
+			continue
+		}
+		extension.Status = ExtStatusOK
+	}
+}
+
+// reset clears the struct keeping the capacity of the lists, so they can be
+// reused without allocations. Strings (sni and protocol names) are allocated
+// on each decode.
+func (i *ExtensionsInfo) reset() {
+	*i = ExtensionsInfo{
+		SignatureSchemes:    i.SignatureSchemes[:0],
+		SupportedVersions:   i.SupportedVersions[:0],
+		SupportedGroups:     i.SupportedGroups[:0],
+		ECPointFormats:      i.ECPointFormats[:0],
+		ALPNs:               i.ALPNs[:0],
+		KeyShareEntries:     i.KeyShareEntries[:0],
+		PSKKeyExchangeModes: i.PSKKeyExchangeModes[:0],
+		PSKIdentities:       i.PSKIdentities[:0],
+		PSKBinders:          i.PSKBinders[:0],
+		StatusRequestsV2:    i.StatusRequestsV2[:0],
+		QUICTransportParams: i.QUICTransportParams[:0],
+		OIDFilters:          i.OIDFilters[:0],
+
+		SignatureSchemesCert:       i.SignatureSchemesCert[:0],
+		ALPSProtocols:              i.ALPSProtocols[:0],
+		DelegatedCredentialSchemes: i.DelegatedCredentialSchemes[:0],
+		TokenBindingKeyParams:      i.TokenBindingKeyParams[:0],
+		SRTPProtectionProfiles:     i.SRTPProtectionProfiles[:0],
+		ClientCertTypes:            i.ClientCertTypes[:0],
+		ServerCertTypes:            i.ServerCertTypes[:0],
+		NPNProtocols:               i.NPNProtocols[:0],
+		TrustedCAKeys:              i.TrustedCAKeys[:0],
+
+		statusRequest: i.statusRequest,
+	}
+}
+
+// MalformedExtensions returns the extensions that couldn't be decoded
+func MalformedExtensions(extensions []Extension) []Extension {
+	var malformed []Extension
+	for _, e := range extensions {
+		if e.Status == ExtStatusMalformed {
+			malformed = append(malformed, e)
 		}
 	}
-	return info, nil
+	return malformed
 }
@@ -2,24 +2,49 @@
 
 package tlsproto
 
-func decodeExtALPN(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+// appendProtocolNameList reads a vector of protocol names with a two bytes
+// length and appends them to protocols
+func appendProtocolNameList(protocols []string, data []byte) ([]string, error) {
 	if len(data) < 2 {
-		return ErrHandshakeExtBadLength
+		return nil, ErrHandshakeExtBadLength
 	}
 
 	alpnLen := int(data[0])<<8 | int(data[1])
 	data = data[2:]
 
 	if len(data) != alpnLen {
-		return ErrHandshakeExtBadLength
+		return nil, ErrHandshakeExtBadLength
 	}
 
 	for len(data) > 0 {
 		stringLen := int(data[0])
 		data = data[1:]
-		info.ALPNs = append(info.ALPNs, string(data[:stringLen]))
+		if len(data) < stringLen {
+			return nil, ErrHandshakeExtBadLength
+		}
+		protocols = append(protocols, string(data[:stringLen]))
 		data = data[stringLen:]
 	}
 
+	return protocols, nil
+}
+
+func decodeExtALPN(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	protocols, err := appendProtocolNameList(info.ALPNs[:0], data)
+	if err != nil {
+		return err
+	}
+	info.ALPNs = protocols
+
+	return nil
+}
+
+func decodeExtApplicationSettings(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	protocols, err := appendProtocolNameList(info.ALPSProtocols[:0], data)
+	if err != nil {
+		return err
+	}
+	info.ALPSProtocols = protocols
+
 	return nil
 }
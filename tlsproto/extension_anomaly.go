@@ -0,0 +1,162 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"fmt"
+
+	"github.com/luisguillenc/tlslayer"
+)
+
+// ExtensionAnomalyType is the type of an anomaly found in the extensions of a hello message
+type ExtensionAnomalyType uint8
+
+// ExtensionAnomalyType possible values
+const (
+	// ExtAnomalyDuplicated extension appears more than once
+	ExtAnomalyDuplicated ExtensionAnomalyType = 1
+	// ExtAnomalyMisordered pre_shared_key is not the last extension
+	ExtAnomalyMisordered ExtensionAnomalyType = 2
+	// ExtAnomalyUnsolicited server sends an extension not offered by the client
+	ExtAnomalyUnsolicited ExtensionAnomalyType = 3
+	// ExtAnomalyForbidden extension is not allowed in a TLS 1.3 server hello
+	ExtAnomalyForbidden ExtensionAnomalyType = 4
+	// ExtAnomalyTLS13Only extension only defined for TLS 1.3 in a TLS 1.2 context
+	ExtAnomalyTLS13Only ExtensionAnomalyType = 5
+)
+
+func (a ExtensionAnomalyType) getDesc() string {
+	switch a {
+	case ExtAnomalyDuplicated:
+		return "duplicated"
+	case ExtAnomalyMisordered:
+		return "misordered"
+	case ExtAnomalyUnsolicited:
+		return "unsolicited"
+	case ExtAnomalyForbidden:
+		return "forbidden"
+	case ExtAnomalyTLS13Only:
+		return "tls13_only"
+	default:
+		return "unknown"
+	}
+}
+
+func (a ExtensionAnomalyType) String() string {
+	return fmt.Sprintf("%s(%d)", a.getDesc(), a)
+}
+
+// ExtensionAnomaly stores an anomaly found in the extensions of a hello message
+type ExtensionAnomaly struct {
+	Anomaly   ExtensionAnomalyType `json:"anomaly"`
+	Extension ExtensionType        `json:"extension"`
+	Handshake HandshakeType        `json:"handshake"`
+}
+
+func (a ExtensionAnomaly) String() string {
+	return fmt.Sprintf("%s %s in %s", a.Anomaly, a.Extension, a.Handshake)
+}
+
+// tls13OnlyExtensions are the extensions that rfc8446 defines only for TLS 1.3
+var tls13OnlyExtensions = map[ExtensionType]bool{
+	ExtPreSharedKey:        true,
+	ExtEarlyData:           true,
+	ExtCookie:              true,
+	ExtPSKKeyExchangeModes: true,
+	ExtCertAuthorities:     true,
+	ExtOIDFilters:          true,
+	ExtPostHandshakeAuth:   true,
+	ExtKeyShare:            true,
+}
+
+// tls13ServerHelloExtensions are the extensions allowed in a TLS 1.3 server hello
+var tls13ServerHelloExtensions = map[ExtensionType]bool{
+	ExtKeyShare:          true,
+	ExtPreSharedKey:      true,
+	ExtSupportedVersions: true,
+}
+
+// tls13HelloRetryExtensions are the extensions allowed in a TLS 1.3 hello retry request
+var tls13HelloRetryExtensions = map[ExtensionType]bool{
+	ExtKeyShare:          true,
+	ExtCookie:            true,
+	ExtSupportedVersions: true,
+}
+
+// checkDuplicated returns anomalies for extensions that appear more than once
+func checkDuplicated(ht HandshakeType, extensions []Extension) []ExtensionAnomaly {
+	var anomalies []ExtensionAnomaly
+	seen := make(map[ExtensionType]int, len(extensions))
+	for _, e := range extensions {
+		seen[e.Type]++
+		if seen[e.Type] == 2 {
+			anomalies = append(anomalies, ExtensionAnomaly{ExtAnomalyDuplicated, e.Type, ht})
+		}
+	}
+	return anomalies
+}
+
+// CheckClientHelloExtensions returns the anomalies found in the extensions of a client hello
+func CheckClientHelloExtensions(ch *ClientHelloData) []ExtensionAnomaly {
+	if ch == nil {
+		return nil
+	}
+	ht := HandshakeTypeClientHello
+	anomalies := checkDuplicated(ht, ch.Extensions)
+	for i, e := range ch.Extensions {
+		if e.Type == ExtPreSharedKey && i != len(ch.Extensions)-1 {
+			anomalies = append(anomalies, ExtensionAnomaly{ExtAnomalyMisordered, e.Type, ht})
+		}
+	}
+	if ch.MaxOfferedVersion().Less(tlslayer.VersionTLS13) {
+		for _, e := range ch.Extensions {
+			if tls13OnlyExtensions[e.Type] {
+				anomalies = append(anomalies, ExtensionAnomaly{ExtAnomalyTLS13Only, e.Type, ht})
+			}
+		}
+	}
+	return anomalies
+}
+
+// CheckServerHelloExtensions returns the anomalies found in the extensions of a server hello,
+// client hello is required to check if server sends extensions not offered by the client
+func CheckServerHelloExtensions(ch *ClientHelloData, sh *ServerHelloData) []ExtensionAnomaly {
+	if sh == nil {
+		return nil
+	}
+	ht := HandshakeTypeServerHello
+	anomalies := checkDuplicated(ht, sh.Extensions)
+	if ch != nil {
+		offered := make(map[ExtensionType]bool, len(ch.Extensions))
+		for _, e := range ch.Extensions {
+			offered[e.Type] = true
+		}
+		// rfc5746: signaling cipher suite is equivalent to an empty renegotiation_info
+		if ch.SecureRenegotiation() {
+			offered[ExtRenegotiationInfo] = true
+		}
+		for _, e := range sh.Extensions {
+			if !offered[e.Type] {
+				anomalies = append(anomalies, ExtensionAnomaly{ExtAnomalyUnsolicited, e.Type, ht})
+			}
+		}
+	}
+	if sh.NegotiatedVersion().TLSVersion() == tlslayer.VersionTLS13 {
+		allowed := tls13ServerHelloExtensions
+		if sh.IsHelloRetryRequest() {
+			allowed = tls13HelloRetryExtensions
+		}
+		for _, e := range sh.Extensions {
+			if !allowed[e.Type] {
+				anomalies = append(anomalies, ExtensionAnomaly{ExtAnomalyForbidden, e.Type, ht})
+			}
+		}
+	} else {
+		for _, e := range sh.Extensions {
+			if tls13OnlyExtensions[e.Type] {
+				anomalies = append(anomalies, ExtensionAnomaly{ExtAnomalyTLS13Only, e.Type, ht})
+			}
+		}
+	}
+	return anomalies
+}
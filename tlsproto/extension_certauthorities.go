@@ -0,0 +1,132 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
+// OIDFilter is a certificate extension oid and its allowed values defined in rfc8446
+type OIDFilter struct {
+	OID    asn1.ObjectIdentifier `json:"oid"`
+	Values []byte                `json:"values,omitempty"`
+}
+
+func (f OIDFilter) String() string {
+	return fmt.Sprintf("%s (len=%d)", f.OID, len(f.Values))
+}
+
+// readDistinguishedNames reads a vector of DER encoded distinguished names
+func readDistinguishedNames(data []byte) ([]pkix.RDNSequence, error) {
+	if len(data) < 2 {
+		return nil, ErrHandshakeExtBadLength
+	}
+	namesLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if len(data) != namesLen {
+		return nil, ErrHandshakeExtBadLength
+	}
+	names := make([]pkix.RDNSequence, 0)
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, ErrHandshakeExtBadLength
+		}
+		nameLen := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+
+		if len(data) < nameLen {
+			return nil, ErrHandshakeExtBadLength
+		}
+		var name pkix.RDNSequence
+		rest, err := asn1.Unmarshal(data[:nameLen], &name)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) > 0 {
+			return nil, ErrHandshakeExtBadValue
+		}
+		names = append(names, name)
+		data = data[nameLen:]
+	}
+	return names, nil
+}
+
+// readOID reads an oid encoded in DER, with or without tag and length
+func readOID(data []byte) (asn1.ObjectIdentifier, error) {
+	var oid asn1.ObjectIdentifier
+	if len(data) > 0 && data[0] == asn1.TagOID {
+		rest, err := asn1.Unmarshal(data, &oid)
+		if err == nil && len(rest) == 0 {
+			return oid, nil
+		}
+	}
+	if len(data) > 127 {
+		return nil, ErrHandshakeExtBadValue
+	}
+	der := make([]byte, 0, len(data)+2)
+	der = append(der, asn1.TagOID, byte(len(data)))
+	der = append(der, data...)
+	rest, err := asn1.Unmarshal(der, &oid)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, ErrHandshakeExtBadValue
+	}
+	return oid, nil
+}
+
+func decodeExtCertAuthorities(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	names, err := readDistinguishedNames(data)
+	if err != nil {
+		return err
+	}
+	info.CertificateAuthorities = names
+
+	return nil
+}
+
+func decodeExtOIDFilters(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) < 2 {
+		return ErrHandshakeExtBadLength
+	}
+	filtersLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if len(data) != filtersLen {
+		return ErrHandshakeExtBadLength
+	}
+	info.OIDFilters = info.OIDFilters[:0]
+	for len(data) > 0 {
+		oidLen := int(data[0])
+		data = data[1:]
+		if oidLen == 0 || len(data) < oidLen {
+			return ErrHandshakeExtBadLength
+		}
+		oid, err := readOID(data[:oidLen])
+		if err != nil {
+			return err
+		}
+		data = data[oidLen:]
+
+		if len(data) < 2 {
+			return ErrHandshakeExtBadLength
+		}
+		valuesLen := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+		if len(data) < valuesLen {
+			return ErrHandshakeExtBadLength
+		}
+		filter := OIDFilter{OID: oid}
+		if valuesLen > 0 {
+			filter.Values = data[:valuesLen]
+		}
+		info.OIDFilters = append(info.OIDFilters, filter)
+		data = data[valuesLen:]
+	}
+
+	return nil
+}
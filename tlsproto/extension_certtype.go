@@ -0,0 +1,82 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import "fmt"
+
+// CertificateType is a certificate type defined in rfc7250
+type CertificateType uint8
+
+// CertificateType possible values
+const (
+	CertificateTypeX509         CertificateType = 0
+	CertificateTypeOpenPGP      CertificateType = 1
+	CertificateTypeRawPublicKey CertificateType = 2
+	CertificateType1609Dot2     CertificateType = 3
+)
+
+func (c CertificateType) getDesc() string {
+	switch c {
+	case CertificateTypeX509:
+		return "X509"
+	case CertificateTypeOpenPGP:
+		return "OpenPGP"
+	case CertificateTypeRawPublicKey:
+		return "RawPublicKey"
+	case CertificateType1609Dot2:
+		return "1609Dot2"
+	default:
+		return "unknown"
+	}
+}
+
+func (c CertificateType) String() string {
+	return fmt.Sprintf("%s(%d)", c.getDesc(), c)
+}
+
+// appendCertificateTypes reads a list of certificate types in client hello or
+// the selected certificate type in server messages reusing the capacity of dst
+func appendCertificateTypes(dst []CertificateType, ht HandshakeType, data []byte) ([]CertificateType, error) {
+	switch ht {
+	case HandshakeTypeClientHello:
+		if len(data) < 1 {
+			return nil, ErrHandshakeExtBadLength
+		}
+		typesLen := int(data[0])
+		data = data[1:]
+
+		if typesLen == 0 || len(data) != typesLen {
+			return nil, ErrHandshakeExtBadLength
+		}
+		types := dst[:0]
+		for i := 0; i < typesLen; i++ {
+			types = append(types, CertificateType(data[i]))
+		}
+		return types, nil
+	default:
+		if len(data) != 1 {
+			return nil, ErrHandshakeExtBadLength
+		}
+		return append(dst[:0], CertificateType(data[0])), nil
+	}
+}
+
+func decodeExtClientCertType(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	types, err := appendCertificateTypes(info.ClientCertTypes, ht, data)
+	if err != nil {
+		return err
+	}
+	info.ClientCertTypes = types
+
+	return nil
+}
+
+func decodeExtServerCertType(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	types, err := appendCertificateTypes(info.ServerCertTypes, ht, data)
+	if err != nil {
+		return err
+	}
+	info.ServerCertTypes = types
+
+	return nil
+}
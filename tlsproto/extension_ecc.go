@@ -116,7 +116,10 @@ func decodeExtSupportedGroups(info *ExtensionsInfo, ht HandshakeType, data []byt
 		return ErrHandshakeExtBadLength
 	}
 
-	info.SupportedGroups = make([]SupportedGroup, groupLen/2)
+	if cap(info.SupportedGroups) < groupLen/2 {
+		info.SupportedGroups = make([]SupportedGroup, groupLen/2)
+	}
+	info.SupportedGroups = info.SupportedGroups[:groupLen/2]
 	for i := 0; i < groupLen/2; i++ {
 		info.SupportedGroups[i] = SupportedGroup(uint16(data[i*2])<<8 | uint16(data[i*2+1]))
 	}
@@ -136,7 +139,10 @@ func decodeExtECPointFormats(info *ExtensionsInfo, ht HandshakeType, data []byte
 		return ErrHandshakeExtBadLength
 	}
 
-	info.ECPointFormats = make([]ECPointFormat, pointLen)
+	if cap(info.ECPointFormats) < pointLen {
+		info.ECPointFormats = make([]ECPointFormat, pointLen)
+	}
+	info.ECPointFormats = info.ECPointFormats[:pointLen]
 	for i := 0; i < pointLen; i++ {
 		info.ECPointFormats[i] = ECPointFormat(data[i])
 	}
@@ -0,0 +1,47 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import "fmt"
+
+// HeartbeatMode is the mode advertised in heartbeat extension defined in rfc6520
+type HeartbeatMode uint8
+
+// HeartbeatMode possible values
+const (
+	HeartbeatPeerAllowedToSend    HeartbeatMode = 1
+	HeartbeatPeerNotAllowedToSend HeartbeatMode = 2
+)
+
+func (m HeartbeatMode) getDesc() string {
+	switch m {
+	case HeartbeatPeerAllowedToSend:
+		return "peer_allowed_to_send"
+	case HeartbeatPeerNotAllowedToSend:
+		return "peer_not_allowed_to_send"
+	default:
+		return "unknown"
+	}
+}
+
+func (m HeartbeatMode) String() string {
+	return fmt.Sprintf("%s(%d)", m.getDesc(), m)
+}
+
+// IsValid method checks if it's a valid value
+func (m HeartbeatMode) IsValid() bool {
+	return m == HeartbeatPeerAllowedToSend || m == HeartbeatPeerNotAllowedToSend
+}
+
+func decodeExtHeartbeat(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) != 1 {
+		return ErrHandshakeExtBadLength
+	}
+	mode := HeartbeatMode(data[0])
+	if !mode.IsValid() {
+		return ErrHandshakeExtBadValue
+	}
+	info.HeartbeatMode = mode
+
+	return nil
+}
@@ -52,13 +52,16 @@ func decodeExtKeyShare(info *ExtensionsInfo, ht HandshakeType, data []byte) erro
 		if len(data) != int(keysLen) {
 			return ErrHandshakeExtBadLength
 		}
-		info.KeyShareEntries = make([]KeyShareEntry, 0)
+		info.KeyShareEntries = info.KeyShareEntries[:0]
 		for len(data) > 0 {
 			if len(data) < 4 {
 				return ErrHandshakeExtBadLength
 			}
 			group := uint16(data[0])<<8 | uint16(data[1])
 			keylen := uint16(data[2])<<8 | uint16(data[3])
+			if len(data) < 4+int(keylen) {
+				return ErrHandshakeExtBadLength
+			}
 
 			entry := KeyShareEntry{}
 			entry.Group = SupportedGroup(group)
@@ -68,12 +71,15 @@ func decodeExtKeyShare(info *ExtensionsInfo, ht HandshakeType, data []byte) erro
 			data = data[4+keylen:]
 		}
 	case HandshakeTypeServerHello:
-		info.KeyShareEntries = make([]KeyShareEntry, 0, 1)
+		info.KeyShareEntries = info.KeyShareEntries[:0]
 		if len(data) < 4 {
 			return ErrHandshakeExtBadLength
 		}
 		group := uint16(data[0])<<8 | uint16(data[1])
 		keylen := uint16(data[2])<<8 | uint16(data[3])
+		if len(data) < 4+int(keylen) {
+			return ErrHandshakeExtBadLength
+		}
 
 		entry := KeyShareEntry{}
 		entry.Group = SupportedGroup(group)
@@ -95,7 +101,10 @@ func decodeExtPSKKeyExchangeModes(info *ExtensionsInfo, ht HandshakeType, data [
 		return ErrHandshakeExtBadLength
 	}
 
-	info.PSKKeyExchangeModes = make([]PSKKeyExchangeMode, int(modesLen), int(modesLen))
+	if cap(info.PSKKeyExchangeModes) < int(modesLen) {
+		info.PSKKeyExchangeModes = make([]PSKKeyExchangeMode, int(modesLen))
+	}
+	info.PSKKeyExchangeModes = info.PSKKeyExchangeModes[:modesLen]
 	for i := 0; i < int(modesLen); i++ {
 		info.PSKKeyExchangeModes[i] = PSKKeyExchangeMode(data[i])
 	}
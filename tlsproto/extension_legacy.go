@@ -0,0 +1,135 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// TrustedAuthorityType is the identifier type of a trusted authority defined in rfc6066
+type TrustedAuthorityType uint8
+
+// TrustedAuthorityType possible values
+const (
+	TrustedAuthorityPreAgreed    TrustedAuthorityType = 0
+	TrustedAuthorityKeySHA1Hash  TrustedAuthorityType = 1
+	TrustedAuthorityX509Name     TrustedAuthorityType = 2
+	TrustedAuthorityCertSHA1Hash TrustedAuthorityType = 3
+)
+
+func (t TrustedAuthorityType) getDesc() string {
+	switch t {
+	case TrustedAuthorityPreAgreed:
+		return "pre_agreed"
+	case TrustedAuthorityKeySHA1Hash:
+		return "key_sha1_hash"
+	case TrustedAuthorityX509Name:
+		return "x509_name"
+	case TrustedAuthorityCertSHA1Hash:
+		return "cert_sha1_hash"
+	default:
+		return "unknown"
+	}
+}
+
+func (t TrustedAuthorityType) String() string {
+	return fmt.Sprintf("%s(%d)", t.getDesc(), t)
+}
+
+// TrustedAuthority stores a ca key identifier from trusted_ca_keys extension
+type TrustedAuthority struct {
+	Type TrustedAuthorityType `json:"type"`
+	// Data is the sha1 hash or the DER encoded distinguished name
+	Data []byte `json:"data,omitempty"`
+}
+
+func (t TrustedAuthority) String() string {
+	return fmt.Sprintf("%s %x", t.Type, t.Data)
+}
+
+func decodeExtTrustedCAKeys(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) == 0 {
+		// server acknowledges the extension
+		return nil
+	}
+	if len(data) < 2 {
+		return ErrHandshakeExtBadLength
+	}
+	listLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if len(data) != listLen {
+		return ErrHandshakeExtBadLength
+	}
+	info.TrustedCAKeys = info.TrustedCAKeys[:0]
+	for len(data) > 0 {
+		authority := TrustedAuthority{Type: TrustedAuthorityType(data[0])}
+		data = data[1:]
+
+		switch authority.Type {
+		case TrustedAuthorityPreAgreed:
+		case TrustedAuthorityKeySHA1Hash, TrustedAuthorityCertSHA1Hash:
+			if len(data) < sha1.Size {
+				return ErrHandshakeExtBadLength
+			}
+			authority.Data = data[:sha1.Size]
+			data = data[sha1.Size:]
+		case TrustedAuthorityX509Name:
+			if len(data) < 2 {
+				return ErrHandshakeExtBadLength
+			}
+			nameLen := int(data[0])<<8 | int(data[1])
+			data = data[2:]
+			if nameLen == 0 || len(data) < nameLen {
+				return ErrHandshakeExtBadLength
+			}
+			authority.Data = data[:nameLen]
+			data = data[nameLen:]
+		default:
+			// unable to know the length of an unknown identifier
+			return ErrHandshakeExtBadValue
+		}
+		info.TrustedCAKeys = append(info.TrustedCAKeys, authority)
+	}
+
+	return nil
+}
+
+func decodeExtClientCertURL(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) != 0 {
+		return ErrHandshakeExtBadLength
+	}
+	info.ClientCertURL = true
+
+	return nil
+}
+
+func decodeExtTruncatedHMAC(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) != 0 {
+		return ErrHandshakeExtBadLength
+	}
+	info.TruncatedHMAC = true
+
+	return nil
+}
+
+func decodeExtNPN(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	info.NPN = true
+	if len(data) == 0 {
+		return nil
+	}
+	// server sends a list of protocols without vector length
+	info.NPNProtocols = info.NPNProtocols[:0]
+	for len(data) > 0 {
+		protoLen := int(data[0])
+		data = data[1:]
+		if len(data) < protoLen {
+			return ErrHandshakeExtBadLength
+		}
+		info.NPNProtocols = append(info.NPNProtocols, string(data[:protoLen]))
+		data = data[protoLen:]
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+func decodeExtPadding(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	info.PaddingLen = uint16(len(data))
+	// padding must be filled with zeros, other values may be a covert channel
+	for _, b := range data {
+		if b != 0 {
+			info.PaddingNonZero = true
+			break
+		}
+	}
+
+	return nil
+}
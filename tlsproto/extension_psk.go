@@ -0,0 +1,93 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import "fmt"
+
+// PSKIdentity is an identity offered by client in pre_shared_key extension, in
+// resumption it's the ticket received in a NewSessionTicket message
+type PSKIdentity struct {
+	Identity            []byte `json:"identity"`
+	ObfuscatedTicketAge uint32 `json:"obfuscatedTicketAge"`
+}
+
+func (p PSKIdentity) String() string {
+	return fmt.Sprintf("(len=%d) age=%d", len(p.Identity), p.ObfuscatedTicketAge)
+}
+
+func decodeExtPreSharedKey(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	switch ht {
+	case HandshakeTypeClientHello:
+		if len(data) < 2 {
+			return ErrHandshakeExtBadLength
+		}
+		identitiesLen := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+		if identitiesLen == 0 || len(data) < identitiesLen {
+			return ErrHandshakeExtBadLength
+		}
+		identities := data[:identitiesLen]
+		data = data[identitiesLen:]
+
+		info.PSKIdentities = info.PSKIdentities[:0]
+		for len(identities) > 0 {
+			if len(identities) < 2 {
+				return ErrHandshakeExtBadLength
+			}
+			idLen := int(identities[0])<<8 | int(identities[1])
+			if idLen == 0 || len(identities) < 2+idLen+4 {
+				return ErrHandshakeExtBadLength
+			}
+			age := identities[2+idLen:]
+			entry := PSKIdentity{
+				Identity:            identities[2 : 2+idLen],
+				ObfuscatedTicketAge: uint32(age[0])<<24 | uint32(age[1])<<16 | uint32(age[2])<<8 | uint32(age[3]),
+			}
+			info.PSKIdentities = append(info.PSKIdentities, entry)
+			identities = identities[2+idLen+4:]
+		}
+
+		if len(data) < 2 {
+			return ErrHandshakeExtBadLength
+		}
+		bindersLen := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+		if len(data) != bindersLen {
+			return ErrHandshakeExtBadLength
+		}
+		info.PSKBinders = info.PSKBinders[:0]
+		for len(data) > 0 {
+			binderLen := int(data[0])
+			if binderLen < 32 || len(data) < 1+binderLen {
+				return ErrHandshakeExtBadLength
+			}
+			info.PSKBinders = append(info.PSKBinders, data[1:1+binderLen])
+			data = data[1+binderLen:]
+		}
+		if len(info.PSKBinders) != len(info.PSKIdentities) {
+			return ErrHandshakeExtBadValue
+		}
+	case HandshakeTypeServerHello:
+		if len(data) != 2 {
+			return ErrHandshakeExtBadLength
+		}
+		info.PSKSelectedIdentity = uint16(data[0])<<8 | uint16(data[1])
+	}
+	info.PreSharedKey = true
+
+	return nil
+}
+
+func decodeExtEarlyData(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if ht == HandshakeTypeNewSessionTicket {
+		if len(data) != 4 {
+			return ErrHandshakeExtBadLength
+		}
+		info.MaxEarlyDataSize = uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	} else if len(data) != 0 {
+		return ErrHandshakeExtBadLength
+	}
+	info.EarlyData = true
+
+	return nil
+}
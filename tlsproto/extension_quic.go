@@ -0,0 +1,171 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// QUICTransportParamID is the identifier of a quic transport parameter defined in rfc9000
+type QUICTransportParamID uint64
+
+// QUIC transport parameters https://www.iana.org/assignments/quic/quic.xhtml#quic-transport
+const (
+	QUICParamOriginalDestinationConnectionID QUICTransportParamID = 0x00
+	QUICParamMaxIdleTimeout                  QUICTransportParamID = 0x01
+	QUICParamStatelessResetToken             QUICTransportParamID = 0x02
+	QUICParamMaxUDPPayloadSize               QUICTransportParamID = 0x03
+	QUICParamInitialMaxData                  QUICTransportParamID = 0x04
+	QUICParamInitialMaxStreamDataBidiLocal   QUICTransportParamID = 0x05
+	QUICParamInitialMaxStreamDataBidiRemote  QUICTransportParamID = 0x06
+	QUICParamInitialMaxStreamDataUni         QUICTransportParamID = 0x07
+	QUICParamInitialMaxStreamsBidi           QUICTransportParamID = 0x08
+	QUICParamInitialMaxStreamsUni            QUICTransportParamID = 0x09
+	QUICParamAckDelayExponent                QUICTransportParamID = 0x0a
+	QUICParamMaxAckDelay                     QUICTransportParamID = 0x0b
+	QUICParamDisableActiveMigration          QUICTransportParamID = 0x0c
+	QUICParamPreferredAddress                QUICTransportParamID = 0x0d
+	QUICParamActiveConnectionIDLimit         QUICTransportParamID = 0x0e
+	QUICParamInitialSourceConnectionID       QUICTransportParamID = 0x0f
+	QUICParamRetrySourceConnectionID         QUICTransportParamID = 0x10
+	QUICParamVersionInformation              QUICTransportParamID = 0x11
+	QUICParamMaxDatagramFrameSize            QUICTransportParamID = 0x20
+	QUICParamGreaseQUICBit                   QUICTransportParamID = 0x2ab2
+)
+
+// quicTransportParamReg stores description and if value is a varint
+var quicTransportParamReg = map[QUICTransportParamID]struct {
+	desc    string
+	integer bool
+}{
+	QUICParamOriginalDestinationConnectionID: {"original_destination_connection_id", false},
+	QUICParamMaxIdleTimeout:                  {"max_idle_timeout", true},
+	QUICParamStatelessResetToken:             {"stateless_reset_token", false},
+	QUICParamMaxUDPPayloadSize:               {"max_udp_payload_size", true},
+	QUICParamInitialMaxData:                  {"initial_max_data", true},
+	QUICParamInitialMaxStreamDataBidiLocal:   {"initial_max_stream_data_bidi_local", true},
+	QUICParamInitialMaxStreamDataBidiRemote:  {"initial_max_stream_data_bidi_remote", true},
+	QUICParamInitialMaxStreamDataUni:         {"initial_max_stream_data_uni", true},
+	QUICParamInitialMaxStreamsBidi:           {"initial_max_streams_bidi", true},
+	QUICParamInitialMaxStreamsUni:            {"initial_max_streams_uni", true},
+	QUICParamAckDelayExponent:                {"ack_delay_exponent", true},
+	QUICParamMaxAckDelay:                     {"max_ack_delay", true},
+	QUICParamDisableActiveMigration:          {"disable_active_migration", false},
+	QUICParamPreferredAddress:                {"preferred_address", false},
+	QUICParamActiveConnectionIDLimit:         {"active_connection_id_limit", true},
+	QUICParamInitialSourceConnectionID:       {"initial_source_connection_id", false},
+	QUICParamRetrySourceConnectionID:         {"retry_source_connection_id", false},
+	QUICParamVersionInformation:              {"version_information", false},
+	QUICParamMaxDatagramFrameSize:            {"max_datagram_frame_size", true},
+	QUICParamGreaseQUICBit:                   {"grease_quic_bit", false},
+}
+
+// IsGREASE returns true if is a reserved transport parameter (31 * N + 27)
+func (id QUICTransportParamID) IsGREASE() bool {
+	return id >= 27 && (id-27)%31 == 0
+}
+
+// IsInteger returns true if the parameter value is encoded as a varint
+func (id QUICTransportParamID) IsInteger() bool {
+	if reg, ok := quicTransportParamReg[id]; ok {
+		return reg.integer
+	}
+	return false
+}
+
+func (id QUICTransportParamID) getDesc() string {
+	if reg, ok := quicTransportParamReg[id]; ok {
+		return reg.desc
+	}
+	if id.IsGREASE() {
+		return "GREASE"
+	}
+	return "unknown"
+}
+
+func (id QUICTransportParamID) String() string {
+	return fmt.Sprintf("%s(%d)", id.getDesc(), id)
+}
+
+// QUICTransportParam is a struct that stores a transport parameter
+type QUICTransportParam struct {
+	ID    QUICTransportParamID `json:"id"`
+	Value []byte               `json:"value,omitempty"`
+	// IntValue is set only if ID is an integer parameter
+	IntValue uint64 `json:"intValue,omitempty"`
+}
+
+// MarshalJSON encodes intValue only for integer parameters, including zero values
+func (p QUICTransportParam) MarshalJSON() ([]byte, error) {
+	type param QUICTransportParam
+	v := struct {
+		param
+		IntValue *uint64 `json:"intValue,omitempty"`
+	}{param: param(p)}
+	if p.ID.IsInteger() {
+		v.IntValue = &p.IntValue
+	}
+	return json.Marshal(v)
+}
+
+func (p QUICTransportParam) String() string {
+	if p.ID.IsInteger() {
+		return fmt.Sprintf("%s=%d", p.ID, p.IntValue)
+	}
+	return fmt.Sprintf("%s (len=%d)", p.ID, len(p.Value))
+}
+
+// readQUICVarint reads a variable-length integer as defined in rfc9000 section 16
+// and returns the value and the number of bytes consumed
+func readQUICVarint(data []byte) (uint64, int, error) {
+	if len(data) < 1 {
+		return 0, 0, ErrHandshakeExtBadLength
+	}
+	length := 1 << (data[0] >> 6)
+	if len(data) < length {
+		return 0, 0, ErrHandshakeExtBadLength
+	}
+	value := uint64(data[0] & 0x3f)
+	for i := 1; i < length; i++ {
+		value = value<<8 | uint64(data[i])
+	}
+	return value, length, nil
+}
+
+func decodeExtQUICTransportParams(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	info.QUICTransportParams = info.QUICTransportParams[:0]
+	for len(data) > 0 {
+		id, n, err := readQUICVarint(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+
+		paramLen, n, err := readQUICVarint(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+
+		if uint64(len(data)) < paramLen {
+			return ErrHandshakeExtBadLength
+		}
+		param := QUICTransportParam{}
+		param.ID = QUICTransportParamID(id)
+		param.Value = data[:paramLen]
+		if param.ID.IsInteger() {
+			param.IntValue, n, err = readQUICVarint(param.Value)
+			if err != nil {
+				return err
+			}
+			if n != len(param.Value) {
+				return ErrHandshakeExtBadLength
+			}
+		}
+		info.QUICTransportParams = append(info.QUICTransportParams, param)
+		data = data[paramLen:]
+	}
+
+	return nil
+}
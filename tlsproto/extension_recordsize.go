@@ -0,0 +1,113 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"fmt"
+
+	"github.com/luisguillenc/tlslayer"
+)
+
+// MaxFragmentLength is the value negotiated with max_fragment_length extension
+type MaxFragmentLength uint8
+
+// MaxFragmentLength possible values
+const (
+	MaxFragmentLength512  MaxFragmentLength = 1
+	MaxFragmentLength1024 MaxFragmentLength = 2
+	MaxFragmentLength2048 MaxFragmentLength = 3
+	MaxFragmentLength4096 MaxFragmentLength = 4
+)
+
+// recordSizeLimitMin is the minimum value allowed by rfc8449
+const recordSizeLimitMin = 64
+
+// Maximum values of record_size_limit, greater values can be sent but the
+// limit is the maximum of the protocol. TLS 1.3 includes the content type.
+const (
+	recordSizeLimitMax   = 1 << 14
+	recordSizeLimitMax13 = 1<<14 + 1
+)
+
+// IsValid method checks if it's a valid value
+func (m MaxFragmentLength) IsValid() bool {
+	return m >= MaxFragmentLength512 && m <= MaxFragmentLength4096
+}
+
+// Size returns the maximum fragment length in bytes, zero if value is not valid
+func (m MaxFragmentLength) Size() uint16 {
+	if !m.IsValid() {
+		return 0
+	}
+	return 1 << (8 + m)
+}
+
+func (m MaxFragmentLength) getDesc() string {
+	if !m.IsValid() {
+		return "unknown"
+	}
+	return fmt.Sprintf("2^%d", 8+m)
+}
+
+func (m MaxFragmentLength) String() string {
+	return fmt.Sprintf("%s(%d)", m.getDesc(), m)
+}
+
+func decodeExtMaxFragLen(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) != 1 {
+		return ErrHandshakeExtBadLength
+	}
+	mfl := MaxFragmentLength(data[0])
+	if !mfl.IsValid() {
+		return ErrHandshakeExtBadValue
+	}
+	info.MaxFragmentLength = mfl
+
+	return nil
+}
+
+func decodeExtRecordSizeLimit(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) != 2 {
+		return ErrHandshakeExtBadLength
+	}
+	limit := uint16(data[0])<<8 | uint16(data[1])
+	if limit < recordSizeLimitMin {
+		return ErrHandshakeExtBadValue
+	}
+	info.RecordSizeLimit = limit
+
+	return nil
+}
+
+// RecordSizeLimits returns the maximum length of the records sent to the client
+// and to the server negotiated with record_size_limit or max_fragment_length
+// extensions. Limits include the expansion allowed for protected records and
+// are zero if nothing was negotiated. A limit is only returned when server hello
+// acknowledges the extension, so in TLS 1.3 record_size_limit is not visible.
+func RecordSizeLimits(ch *ClientHelloData, sh *ServerHelloData) (toClient uint16, toServer uint16) {
+	if ch == nil || sh == nil || ch.ExtInfo == nil || sh.ExtInfo == nil {
+		return 0, 0
+	}
+	expansion := tlslayer.MaxTLS12RecordExpansion
+	maxLimit := uint16(recordSizeLimitMax)
+	if sh.NegotiatedVersion().TLSVersion() == tlslayer.VersionTLS13 {
+		expansion = tlslayer.MaxTLS13RecordExpansion
+		maxLimit = recordSizeLimitMax13
+	}
+	// record_size_limit takes precedence over max_fragment_length (rfc8449)
+	if ch.ExtInfo.RecordSizeLimit > 0 && sh.ExtInfo.RecordSizeLimit > 0 {
+		toClient, toServer = ch.ExtInfo.RecordSizeLimit, sh.ExtInfo.RecordSizeLimit
+		if toClient > maxLimit {
+			toClient = maxLimit
+		}
+		if toServer > maxLimit {
+			toServer = maxLimit
+		}
+		return toClient + expansion, toServer + expansion
+	}
+	if sh.ExtInfo.MaxFragmentLength.IsValid() && sh.ExtInfo.MaxFragmentLength == ch.ExtInfo.MaxFragmentLength {
+		limit := sh.ExtInfo.MaxFragmentLength.Size() + expansion
+		return limit, limit
+	}
+	return 0, 0
+}
@@ -0,0 +1,39 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+func decodeExtRenegotiationInfo(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) < 1 {
+		return ErrHandshakeExtBadLength
+	}
+	connLen := int(data[0])
+	data = data[1:]
+
+	if len(data) != connLen {
+		return ErrHandshakeExtBadLength
+	}
+	info.RenegotiationInfo = true
+	if connLen > 0 {
+		info.RenegotiatedConnection = data
+	}
+
+	return nil
+}
+
+func decodeExtExtendedMasterSecret(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) != 0 {
+		return ErrHandshakeExtBadLength
+	}
+	info.ExtendedMasterSecret = true
+
+	return nil
+}
+
+func decodeExtEncryptThenMAC(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) != 0 {
+		return ErrHandshakeExtBadLength
+	}
+	info.EncryptThenMAC = true
+
+	return nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+// EmptySessionTicket returns true if an empty session ticket was offered
+func (i *ExtensionsInfo) EmptySessionTicket() bool {
+	return i.SessionTicket && i.SessionTicketLen == 0
+}
+
+func decodeExtSessionTicket(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	info.SessionTicket = true
+	info.SessionTicketLen = uint16(len(data))
+	if len(data) > 0 {
+		info.SessionTicketData = data
+	}
+
+	return nil
+}
+
+func decodeExtCookie(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) < 2 {
+		return ErrHandshakeExtBadLength
+	}
+	cookieLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if cookieLen == 0 || len(data) != cookieLen {
+		return ErrHandshakeExtBadLength
+	}
+	info.Cookie = data
+
+	return nil
+}
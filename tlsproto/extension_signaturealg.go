@@ -58,23 +58,59 @@ func (s SignatureScheme) String() string {
 	return fmt.Sprintf("%s(%d)", s.getDesc(), s)
 }
 
-func decodeExtSignatureAlgs(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+// appendSignatureSchemes reads a vector of signature schemes with a two bytes
+// length reusing the capacity of dst
+func appendSignatureSchemes(dst []SignatureScheme, data []byte) ([]SignatureScheme, error) {
 	if len(data) < 2 {
-		return ErrHandshakeExtBadLength
+		return nil, ErrHandshakeExtBadLength
 	}
 	sigLen := int(data[0])<<8 | int(data[1])
 
 	data = data[2:]
 
 	if len(data) < sigLen {
-		return ErrHandshakeExtBadLength
+		return nil, ErrHandshakeExtBadLength
 	}
 
-	info.SignatureSchemes = make([]SignatureScheme, sigLen/2)
+	schemes := dst[:0]
+	if cap(schemes) < sigLen/2 {
+		schemes = make([]SignatureScheme, sigLen/2)
+	}
+	schemes = schemes[:sigLen/2]
 
 	for i := 0; i < sigLen/2; i++ {
-		info.SignatureSchemes[i] = SignatureScheme(uint16(data[i*2])<<8 | uint16(data[i*2+1]))
+		schemes[i] = SignatureScheme(uint16(data[i*2])<<8 | uint16(data[i*2+1]))
+	}
+
+	return schemes, nil
+}
+
+func decodeExtSignatureAlgs(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	schemes, err := appendSignatureSchemes(info.SignatureSchemes, data)
+	if err != nil {
+		return err
+	}
+	info.SignatureSchemes = schemes
+
+	return nil
+}
+
+func decodeExtSignatureAlgsCert(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	schemes, err := appendSignatureSchemes(info.SignatureSchemesCert, data)
+	if err != nil {
+		return err
+	}
+	info.SignatureSchemesCert = schemes
+
+	return nil
+}
+
+func decodeExtDelegatedCredentials(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	schemes, err := appendSignatureSchemes(info.DelegatedCredentialSchemes, data)
+	if err != nil {
+		return err
 	}
+	info.DelegatedCredentialSchemes = schemes
 
 	return nil
 }
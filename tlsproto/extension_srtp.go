@@ -0,0 +1,63 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import "fmt"
+
+// SRTPProtectionProfile is a dtls-srtp protection profile defined in rfc5764
+type SRTPProtectionProfile uint16
+
+var srtpProtectionProfileReg = map[SRTPProtectionProfile]string{
+	0x0001: "SRTP_AES128_CM_HMAC_SHA1_80",
+	0x0002: "SRTP_AES128_CM_HMAC_SHA1_32",
+	0x0005: "SRTP_NULL_HMAC_SHA1_80",
+	0x0006: "SRTP_NULL_HMAC_SHA1_32",
+	0x0007: "SRTP_AEAD_AES_128_GCM",
+	0x0008: "SRTP_AEAD_AES_256_GCM",
+}
+
+func (p SRTPProtectionProfile) getDesc() string {
+	if name, ok := srtpProtectionProfileReg[p]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (p SRTPProtectionProfile) String() string {
+	return fmt.Sprintf("%s(%d)", p.getDesc(), p)
+}
+
+func decodeExtUseSRTP(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) < 2 {
+		return ErrHandshakeExtBadLength
+	}
+	profilesLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if len(data) < profilesLen || profilesLen%2 != 0 {
+		return ErrHandshakeExtBadLength
+	}
+	if cap(info.SRTPProtectionProfiles) < profilesLen/2 {
+		info.SRTPProtectionProfiles = make([]SRTPProtectionProfile, profilesLen/2)
+	}
+	info.SRTPProtectionProfiles = info.SRTPProtectionProfiles[:profilesLen/2]
+	for i := 0; i < profilesLen/2; i++ {
+		info.SRTPProtectionProfiles[i] = SRTPProtectionProfile(uint16(data[i*2])<<8 | uint16(data[i*2+1]))
+	}
+	data = data[profilesLen:]
+
+	// srtp_mki
+	if len(data) < 1 {
+		return ErrHandshakeExtBadLength
+	}
+	mkiLen := int(data[0])
+	data = data[1:]
+	if len(data) != mkiLen {
+		return ErrHandshakeExtBadLength
+	}
+	if mkiLen > 0 {
+		info.SRTPMKI = data
+	}
+
+	return nil
+}
@@ -2,17 +2,151 @@
 
 package tlsproto
 
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
 const (
 	// OCSPStatusRequest constant used for status request oscp
 	OCSPStatusRequest uint8 = 1
+	// OCSPMultiStatusRequest constant used for status request v2 ocsp_multi
+	OCSPMultiStatusRequest uint8 = 2
 )
 
+// oidOCSPNonce is the oid of the nonce extension defined in rfc6960
+var oidOCSPNonce = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+// CertificateStatusRequest stores an ocsp request from status_request or status_request_v2 extensions
+type CertificateStatusRequest struct {
+	StatusType   uint8            `json:"statusType"`
+	ResponderIDs [][]byte         `json:"responderIDs,omitempty"`
+	Extensions   []pkix.Extension `json:"extensions,omitempty"`
+	Nonce        []byte           `json:"nonce,omitempty"`
+}
+
+func (r *CertificateStatusRequest) String() string {
+	return fmt.Sprintf("type=%d responders=%d extensions=%d nonce=%x",
+		r.StatusType, len(r.ResponderIDs), len(r.Extensions), r.Nonce)
+}
+
+// readOCSPStatusRequest reads an OCSPStatusRequest struct defined in rfc6066
+func readOCSPStatusRequest(req *CertificateStatusRequest, data []byte) error {
+	if len(data) < 2 {
+		return ErrHandshakeExtBadLength
+	}
+	respondersLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if len(data) < respondersLen {
+		return ErrHandshakeExtBadLength
+	}
+	responders := data[:respondersLen]
+	data = data[respondersLen:]
+	for len(responders) > 0 {
+		if len(responders) < 2 {
+			return ErrHandshakeExtBadLength
+		}
+		idLen := int(responders[0])<<8 | int(responders[1])
+		responders = responders[2:]
+		if idLen == 0 || len(responders) < idLen {
+			return ErrHandshakeExtBadLength
+		}
+		req.ResponderIDs = append(req.ResponderIDs, responders[:idLen])
+		responders = responders[idLen:]
+	}
+
+	if len(data) < 2 {
+		return ErrHandshakeExtBadLength
+	}
+	extensionsLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if len(data) != extensionsLen {
+		return ErrHandshakeExtBadLength
+	}
+	if extensionsLen > 0 {
+		rest, err := asn1.Unmarshal(data, &req.Extensions)
+		if err != nil {
+			return err
+		}
+		if len(rest) > 0 {
+			return ErrHandshakeExtBadValue
+		}
+		for _, ext := range req.Extensions {
+			if ext.Id.Equal(oidOCSPNonce) {
+				req.Nonce = ext.Value
+			}
+		}
+	}
+
+	return nil
+}
+
 func decodeExtStatusRequest(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
-	if len(data) > 1 {
-		switch data[0] {
-		case OCSPStatusRequest:
-			info.OSCP = true
+	if len(data) == 0 {
+		// server acknowledges that it will send a certificate status
+		if ht != HandshakeTypeClientHello {
+			info.StatusRequestAck = true
+		}
+		return nil
+	}
+	req := info.statusRequest
+	if req == nil {
+		req = &CertificateStatusRequest{}
+		info.statusRequest = req
+	}
+	*req = CertificateStatusRequest{StatusType: data[0], ResponderIDs: req.ResponderIDs[:0]}
+	switch req.StatusType {
+	case OCSPStatusRequest:
+		// flag is kept if the request is malformed
+		info.OSCP = true
+		err := readOCSPStatusRequest(req, data[1:])
+		if err != nil {
+			return err
+		}
+	}
+	info.StatusRequest = req
+
+	return nil
+}
+
+func decodeExtStatusRequestV2(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	info.StatusRequestV2 = true
+	if len(data) == 0 {
+		return nil
+	}
+	if len(data) < 2 {
+		return ErrHandshakeExtBadLength
+	}
+	listLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	if len(data) != listLen {
+		return ErrHandshakeExtBadLength
+	}
+	info.StatusRequestsV2 = info.StatusRequestsV2[:0]
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return ErrHandshakeExtBadLength
+		}
+		req := CertificateStatusRequest{StatusType: data[0]}
+		reqLen := int(data[1])<<8 | int(data[2])
+		data = data[3:]
+
+		if len(data) < reqLen {
+			return ErrHandshakeExtBadLength
+		}
+		switch req.StatusType {
+		case OCSPStatusRequest, OCSPMultiStatusRequest:
+			err := readOCSPStatusRequest(&req, data[:reqLen])
+			if err != nil {
+				return err
+			}
 		}
+		info.StatusRequestsV2 = append(info.StatusRequestsV2, req)
+		data = data[reqLen:]
 	}
 
 	return nil
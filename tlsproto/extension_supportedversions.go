@@ -42,6 +42,12 @@ func (sv SupportedVersion) getDesc() string {
 		return "TLS_1.2"
 	case tlslayer.VersionTLS13:
 		return "TLS_1.3"
+	case tlslayer.VersionDTLS10:
+		return "DTLS_1.0"
+	case tlslayer.VersionDTLS12:
+		return "DTLS_1.2"
+	case tlslayer.VersionDTLS13:
+		return "DTLS_1.3"
 	default:
 		return "unknown"
 	}
@@ -51,6 +57,30 @@ func (sv SupportedVersion) String() string {
 	return fmt.Sprintf("%s(%d)", sv.getDesc(), sv)
 }
 
+// Version returns the protocol version, draft versions are returned as tls 1.3
+func (sv SupportedVersion) Version() tlslayer.ProtocolVersion {
+	if sv.IsDraft() {
+		return tlslayer.VersionTLS13
+	}
+	return tlslayer.ProtocolVersion(sv)
+}
+
+// getSupportedVersions returns supported versions from decoded info or decoding the raw extension
+func getSupportedVersions(ht HandshakeType, extensions []Extension, info *ExtensionsInfo) []SupportedVersion {
+	if info != nil {
+		return info.SupportedVersions
+	}
+	for _, e := range extensions {
+		if e.Type == ExtSupportedVersions {
+			tmp := &ExtensionsInfo{}
+			if decodeExtSupportedVersions(tmp, ht, e.Payload) == nil {
+				return tmp.SupportedVersions
+			}
+		}
+	}
+	return nil
+}
+
 func decodeExtSupportedVersions(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
 	switch ht {
 	case HandshakeTypeClientHello:
@@ -63,7 +93,10 @@ func decodeExtSupportedVersions(info *ExtensionsInfo, ht HandshakeType, data []b
 		if len(data) < verLen {
 			return ErrHandshakeExtBadLength
 		}
-		info.SupportedVersions = make([]SupportedVersion, verLen/2)
+		if cap(info.SupportedVersions) < verLen/2 {
+			info.SupportedVersions = make([]SupportedVersion, verLen/2)
+		}
+		info.SupportedVersions = info.SupportedVersions[:verLen/2]
 		for i := 0; i < verLen/2; i++ {
 			info.SupportedVersions[i] = SupportedVersion(uint16(data[i*2])<<8 | uint16(data[i*2+1]))
 		}
@@ -71,9 +104,8 @@ func decodeExtSupportedVersions(info *ExtensionsInfo, ht HandshakeType, data []b
 		if len(data) != 2 {
 			return ErrHandshakeExtBadLength
 		}
-		info.SupportedVersions = make([]SupportedVersion, 0, 1)
 		sup := SupportedVersion(uint16(data[0])<<8 | uint16(data[1]))
-		info.SupportedVersions = append(info.SupportedVersions, sup)
+		info.SupportedVersions = append(info.SupportedVersions[:0], sup)
 	}
 
 	return nil
@@ -0,0 +1,545 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlsproto
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"testing"
+
+	"github.com/luisguillenc/tlslayer"
+)
+
+var testExtQUICTransportParams1 = []byte{
+	0x01, 0x02, 0x67, 0x10,
+	0x04, 0x04, 0x80, 0x98, 0x96, 0x80,
+	0x0e, 0x01, 0x04,
+	0x40, 0x3a, 0x03, 0x01, 0x02, 0x03,
+	0x0f, 0x08, 0x83, 0x94, 0xc8, 0xf0, 0x3e, 0x51, 0x57, 0x08,
+}
+
+func TestExtQUICTransportParams(t *testing.T) {
+	info := &ExtensionsInfo{}
+	err := decodeExtQUICTransportParams(info, HandshakeTypeClientHello, testExtQUICTransportParams1)
+	if err != nil {
+		t.Fatal("decoding quic_transport_parameters:", err)
+	}
+	params := info.QUICTransportParams
+	if len(params) != 5 {
+		t.Fatalf("expected params: 5, got: %v", len(params))
+	}
+	if params[0].ID != QUICParamMaxIdleTimeout || params[0].IntValue != 10000 {
+		t.Errorf("expected max_idle_timeout=10000, got: %v", params[0])
+	}
+	if params[1].ID != QUICParamInitialMaxData || params[1].IntValue != 10000000 {
+		t.Errorf("expected initial_max_data=10000000, got: %v", params[1])
+	}
+	if params[2].ID != QUICParamActiveConnectionIDLimit || params[2].IntValue != 4 {
+		t.Errorf("expected active_connection_id_limit=4, got: %v", params[2])
+	}
+	if !params[3].ID.IsGREASE() {
+		t.Errorf("expected grease param, got: %v", params[3])
+	}
+	if len(params[3].Value) != 3 {
+		t.Errorf("expected grease value: (len=3), got: (len=%v)", len(params[3].Value))
+	}
+	cid := []byte{0x83, 0x94, 0xc8, 0xf0, 0x3e, 0x51, 0x57, 0x08}
+	if params[4].ID != QUICParamInitialSourceConnectionID || !bytes.Equal(params[4].Value, cid) {
+		t.Errorf("expected initial_source_connection_id %x, got: %v", cid, params[4])
+	}
+
+	// zero values of integer parameters are encoded
+	if err := decodeExtQUICTransportParams(info, HandshakeTypeClientHello, []byte{0x01, 0x01, 0x00}); err != nil {
+		t.Fatal("decoding quic_transport_parameters:", err)
+	}
+	data, err := json.Marshal(info.QUICTransportParams[0])
+	if err != nil {
+		t.Fatal("encoding param:", err)
+	}
+	if !bytes.Contains(data, []byte(`"intValue":0`)) {
+		t.Errorf("expected intValue in json, got: %s", data)
+	}
+	data, err = json.Marshal(params[4])
+	if err != nil {
+		t.Fatal("encoding param:", err)
+	}
+	if bytes.Contains(data, []byte(`intValue`)) {
+		t.Errorf("unexpected intValue in json, got: %s", data)
+	}
+
+	err = decodeExtQUICTransportParams(info, HandshakeTypeClientHello, testExtQUICTransportParams1[:5])
+	if err != ErrHandshakeExtBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadLength, err)
+	}
+}
+
+func TestExtRenegotiationInfo(t *testing.T) {
+	info := &ExtensionsInfo{}
+	err := decodeExtRenegotiationInfo(info, HandshakeTypeClientHello, []byte{0x04, 0x01, 0x02, 0x03, 0x04})
+	if err != nil {
+		t.Fatal("decoding renegotiation_info:", err)
+	}
+	if !info.RenegotiationInfo {
+		t.Error("expected renegotiation_info")
+	}
+	if !bytes.Equal(info.RenegotiatedConnection, []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("unexpected renegotiated_connection: %x", info.RenegotiatedConnection)
+	}
+
+	err = decodeExtRenegotiationInfo(info, HandshakeTypeClientHello, []byte{0x04, 0x01})
+	if err != ErrHandshakeExtBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadLength, err)
+	}
+
+	ch := &ClientHelloData{CipherSuites: []CipherSuite{0xc02f, CipherSuiteEmptyRenegotiationInfoSCSV}}
+	if !ch.SecureRenegotiation() {
+		t.Error("expected secure renegotiation from scsv")
+	}
+}
+
+func TestExtExtendedMasterSecret(t *testing.T) {
+	info := &ExtensionsInfo{}
+	if err := decodeExtExtendedMasterSecret(info, HandshakeTypeServerHello, []byte{}); err != nil {
+		t.Fatal("decoding extended_master_secret:", err)
+	}
+	if !info.ExtendedMasterSecret {
+		t.Error("expected extended_master_secret")
+	}
+	if err := decodeExtEncryptThenMAC(info, HandshakeTypeServerHello, []byte{0x00}); err != ErrHandshakeExtBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadLength, err)
+	}
+}
+
+func TestExtPadding(t *testing.T) {
+	info := &ExtensionsInfo{}
+	decodeExtPadding(info, HandshakeTypeClientHello, make([]byte, 12))
+	if info.PaddingLen != 12 || info.PaddingNonZero {
+		t.Errorf("expected zero padding (len=12), got: (len=%v) nonzero=%v", info.PaddingLen, info.PaddingNonZero)
+	}
+	decodeExtPadding(info, HandshakeTypeClientHello, []byte{0x00, 0x00, 0x41, 0x00})
+	if !info.PaddingNonZero {
+		t.Error("expected non zero padding")
+	}
+}
+
+func TestExtCookie(t *testing.T) {
+	info := &ExtensionsInfo{}
+	if err := decodeExtCookie(info, HandshakeTypeClientHello, []byte{0x00, 0x02, 0xca, 0xfe}); err != nil {
+		t.Fatal("decoding cookie:", err)
+	}
+	if !bytes.Equal(info.Cookie, []byte{0xca, 0xfe}) {
+		t.Errorf("unexpected cookie: %x", info.Cookie)
+	}
+	if err := decodeExtCookie(info, HandshakeTypeClientHello, []byte{0x00, 0x00}); err != ErrHandshakeExtBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadLength, err)
+	}
+}
+
+func TestExtRecordSizeLimits(t *testing.T) {
+	chInfo := &ExtensionsInfo{}
+	if err := decodeExtMaxFragLen(chInfo, HandshakeTypeClientHello, []byte{0x02}); err != nil {
+		t.Fatal("decoding max_fragment_length:", err)
+	}
+	if chInfo.MaxFragmentLength.Size() != 1024 {
+		t.Errorf("expected max_fragment_length: 1024, got: %v", chInfo.MaxFragmentLength.Size())
+	}
+	if err := decodeExtMaxFragLen(chInfo, HandshakeTypeClientHello, []byte{0x05}); err != ErrHandshakeExtBadValue {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadValue, err)
+	}
+	if err := decodeExtRecordSizeLimit(chInfo, HandshakeTypeClientHello, []byte{0x00, 0x10}); err != ErrHandshakeExtBadValue {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadValue, err)
+	}
+
+	ch := &ClientHelloData{ExtInfo: chInfo}
+	sh := &ServerHelloData{ExtInfo: &ExtensionsInfo{}}
+	toClient, toServer := RecordSizeLimits(ch, sh)
+	if toClient != 0 || toServer != 0 {
+		t.Errorf("expected no limits, got: %v, %v", toClient, toServer)
+	}
+	sh.ExtInfo.MaxFragmentLength = MaxFragmentLength1024
+	toClient, toServer = RecordSizeLimits(ch, sh)
+	if toClient != 1024+tlslayer.MaxTLS12RecordExpansion || toServer != toClient {
+		t.Errorf("expected limits: %v, got: %v, %v", 1024+tlslayer.MaxTLS12RecordExpansion, toClient, toServer)
+	}
+
+	decodeExtRecordSizeLimit(chInfo, HandshakeTypeClientHello, []byte{0x10, 0x00})
+	decodeExtRecordSizeLimit(sh.ExtInfo, HandshakeTypeServerHello, []byte{0x40, 0x00})
+	toClient, toServer = RecordSizeLimits(ch, sh)
+	if toClient != 4096+tlslayer.MaxTLS12RecordExpansion || toServer != 16384+tlslayer.MaxTLS12RecordExpansion {
+		t.Errorf("unexpected record_size_limit limits: %v, %v", toClient, toServer)
+	}
+
+	// limits greater than the maximum of the protocol
+	decodeExtRecordSizeLimit(chInfo, HandshakeTypeClientHello, []byte{0xff, 0xff})
+	toClient, toServer = RecordSizeLimits(ch, sh)
+	if toClient != 16384+tlslayer.MaxTLS12RecordExpansion || toServer != 16384+tlslayer.MaxTLS12RecordExpansion {
+		t.Errorf("unexpected record_size_limit limits: %v, %v", toClient, toServer)
+	}
+	sh.ExtInfo.SupportedVersions = []SupportedVersion{0x0304}
+	toClient, _ = RecordSizeLimits(ch, sh)
+	if toClient != 16385+tlslayer.MaxTLS13RecordExpansion {
+		t.Errorf("unexpected tls 1.3 record_size_limit limit: %v", toClient)
+	}
+}
+
+func TestExtCertAuthorities(t *testing.T) {
+	name := pkix.Name{CommonName: "Test CA", Organization: []string{"tlslayer"}}
+	der, err := asn1.Marshal(name.ToRDNSequence())
+	if err != nil {
+		t.Fatal("marshalling name:", err)
+	}
+	data := []byte{byte((len(der) + 2) >> 8), byte(len(der) + 2), byte(len(der) >> 8), byte(len(der))}
+	data = append(data, der...)
+
+	info := &ExtensionsInfo{}
+	if err := decodeExtCertAuthorities(info, HandshakeTypeCertificateRequest, data); err != nil {
+		t.Fatal("decoding certificate_authorities:", err)
+	}
+	if len(info.CertificateAuthorities) != 1 {
+		t.Fatalf("expected certificate_authorities: 1, got: %v", len(info.CertificateAuthorities))
+	}
+	var got pkix.Name
+	got.FillFromRDNSequence(&info.CertificateAuthorities[0])
+	if got.CommonName != "Test CA" {
+		t.Errorf("expected commonname: Test CA, got: %v", got.CommonName)
+	}
+}
+
+func TestExtOIDFilters(t *testing.T) {
+	data := []byte{
+		0x00, 0x10,
+		0x03, 0x55, 0x1d, 0x25, 0x00, 0x0a, 0x30, 0x08, 0x06, 0x06, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07,
+	}
+	info := &ExtensionsInfo{}
+	if err := decodeExtOIDFilters(info, HandshakeTypeCertificateRequest, data); err != nil {
+		t.Fatal("decoding oid_filters:", err)
+	}
+	if len(info.OIDFilters) != 1 {
+		t.Fatalf("expected oid_filters: 1, got: %v", len(info.OIDFilters))
+	}
+	if !info.OIDFilters[0].OID.Equal(asn1.ObjectIdentifier{2, 5, 29, 37}) {
+		t.Errorf("expected oid: 2.5.29.37, got: %v", info.OIDFilters[0].OID)
+	}
+	if len(info.OIDFilters[0].Values) != 10 {
+		t.Errorf("expected values: (len=10), got: (len=%v)", len(info.OIDFilters[0].Values))
+	}
+}
+
+func TestExtSignatureAlgsCert(t *testing.T) {
+	info := &ExtensionsInfo{}
+	data := []byte{0x00, 0x04, 0x04, 0x03, 0x08, 0x04}
+	if err := decodeExtSignatureAlgsCert(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding signature_algorithms_cert:", err)
+	}
+	if len(info.SignatureSchemesCert) != 2 || info.SignatureSchemesCert[1] != SignatureScheme(0x0804) {
+		t.Errorf("unexpected signature_algorithms_cert: %v", info.SignatureSchemesCert)
+	}
+	if len(info.SignatureSchemes) != 0 {
+		t.Errorf("unexpected signature_algorithms: %v", info.SignatureSchemes)
+	}
+}
+
+func TestExtApplicationSettings(t *testing.T) {
+	info := &ExtensionsInfo{}
+	data := []byte{0x00, 0x03, 0x02, 0x68, 0x32}
+	if err := decodeExtApplicationSettings(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding application_settings:", err)
+	}
+	if len(info.ALPSProtocols) != 1 || info.ALPSProtocols[0] != "h2" {
+		t.Errorf("expected alps: [h2], got: %v", info.ALPSProtocols)
+	}
+	if err := decodeExtApplicationSettings(info, HandshakeTypeClientHello, []byte{0x00, 0x02, 0x05, 0x68}); err != ErrHandshakeExtBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadLength, err)
+	}
+}
+
+func TestExtTokenBinding(t *testing.T) {
+	info := &ExtensionsInfo{}
+	data := []byte{0x00, 0x0d, 0x02, 0x02, 0x00}
+	if err := decodeExtTokenBinding(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding token_binding:", err)
+	}
+	if info.TokenBindingVersion != 0x000d {
+		t.Errorf("expected token binding version: 0x000d, got: %#04x", info.TokenBindingVersion)
+	}
+	if len(info.TokenBindingKeyParams) != 2 || info.TokenBindingKeyParams[0] != TokenBindingECDSAP256 {
+		t.Errorf("unexpected token binding key parameters: %v", info.TokenBindingKeyParams)
+	}
+}
+
+func TestExtDelegatedCredentials(t *testing.T) {
+	info := &ExtensionsInfo{}
+	data := []byte{0x00, 0x06, 0x04, 0x03, 0x05, 0x03, 0x06, 0x03}
+	if err := decodeExtDelegatedCredentials(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding delegated_credentials:", err)
+	}
+	if len(info.DelegatedCredentialSchemes) != 3 {
+		t.Errorf("expected delegated_credentials: 3, got: %v", info.DelegatedCredentialSchemes)
+	}
+}
+
+func TestExtUseSRTP(t *testing.T) {
+	info := &ExtensionsInfo{}
+	data := []byte{0x00, 0x04, 0x00, 0x07, 0x00, 0x01, 0x02, 0xab, 0xcd}
+	if err := decodeExtUseSRTP(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding use_srtp:", err)
+	}
+	if len(info.SRTPProtectionProfiles) != 2 || info.SRTPProtectionProfiles[0] != SRTPProtectionProfile(0x0007) {
+		t.Errorf("unexpected srtp profiles: %v", info.SRTPProtectionProfiles)
+	}
+	if !bytes.Equal(info.SRTPMKI, []byte{0xab, 0xcd}) {
+		t.Errorf("unexpected srtp mki: %x", info.SRTPMKI)
+	}
+}
+
+func TestExtHeartbeat(t *testing.T) {
+	info := &ExtensionsInfo{}
+	if err := decodeExtHeartbeat(info, HandshakeTypeServerHello, []byte{0x01}); err != nil {
+		t.Fatal("decoding heartbeat:", err)
+	}
+	if info.HeartbeatMode != HeartbeatPeerAllowedToSend {
+		t.Errorf("expected heartbeat mode: %v, got: %v", HeartbeatPeerAllowedToSend, info.HeartbeatMode)
+	}
+	if err := decodeExtHeartbeat(info, HandshakeTypeServerHello, []byte{0x03}); err != ErrHandshakeExtBadValue {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadValue, err)
+	}
+}
+
+func TestExtCertTypes(t *testing.T) {
+	info := &ExtensionsInfo{}
+	if err := decodeExtServerCertType(info, HandshakeTypeClientHello, []byte{0x02, 0x02, 0x00}); err != nil {
+		t.Fatal("decoding server_certificate_type:", err)
+	}
+	if len(info.ServerCertTypes) != 2 || info.ServerCertTypes[0] != CertificateTypeRawPublicKey {
+		t.Errorf("unexpected server certificate types: %v", info.ServerCertTypes)
+	}
+	if err := decodeExtClientCertType(info, HandshakeTypeServerHello, []byte{0x00}); err != nil {
+		t.Fatal("decoding client_certificate_type:", err)
+	}
+	if len(info.ClientCertTypes) != 1 || info.ClientCertTypes[0] != CertificateTypeX509 {
+		t.Errorf("unexpected client certificate types: %v", info.ClientCertTypes)
+	}
+}
+
+func TestExtStatusRequest(t *testing.T) {
+	exts, err := asn1.Marshal([]pkix.Extension{{Id: oidOCSPNonce, Value: []byte{0x04, 0x02, 0xaa, 0xbb}}})
+	if err != nil {
+		t.Fatal("marshalling extensions:", err)
+	}
+	ocspReq := []byte{0x00, 0x05, 0x00, 0x03, 0x01, 0x02, 0x03}
+	ocspReq = append(ocspReq, byte(len(exts)>>8), byte(len(exts)))
+	ocspReq = append(ocspReq, exts...)
+
+	info := &ExtensionsInfo{}
+	data := append([]byte{OCSPStatusRequest}, ocspReq...)
+	if err := decodeExtStatusRequest(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding status_request:", err)
+	}
+	if !info.OSCP || info.StatusRequest == nil {
+		t.Fatal("expected ocsp status request")
+	}
+	if len(info.StatusRequest.ResponderIDs) != 1 || len(info.StatusRequest.ResponderIDs[0]) != 3 {
+		t.Errorf("unexpected responder ids: %v", info.StatusRequest.ResponderIDs)
+	}
+	if !bytes.Equal(info.StatusRequest.Nonce, []byte{0x04, 0x02, 0xaa, 0xbb}) {
+		t.Errorf("unexpected nonce: %x", info.StatusRequest.Nonce)
+	}
+
+	info = &ExtensionsInfo{}
+	data = []byte{0x00, byte(len(ocspReq) + 3), OCSPMultiStatusRequest, 0x00, byte(len(ocspReq))}
+	data = append(data, ocspReq...)
+	if err := decodeExtStatusRequestV2(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding status_request_v2:", err)
+	}
+	if !info.StatusRequestV2 || len(info.StatusRequestsV2) != 1 {
+		t.Fatalf("expected status_request_v2 items: 1, got: %v", len(info.StatusRequestsV2))
+	}
+	if info.StatusRequestsV2[0].StatusType != OCSPMultiStatusRequest || info.StatusRequestsV2[0].Nonce == nil {
+		t.Errorf("unexpected status_request_v2 item: %v", &info.StatusRequestsV2[0])
+	}
+
+	// flag is kept if the request is malformed
+	info = &ExtensionsInfo{}
+	if err := decodeExtStatusRequest(info, HandshakeTypeClientHello, []byte{OCSPStatusRequest, 0x00, 0x05, 0x00}); err != ErrHandshakeExtBadLength || !info.OSCP {
+		t.Errorf("expected ocsp requested with error: %v, got: %v", ErrHandshakeExtBadLength, err)
+	}
+
+	info = &ExtensionsInfo{}
+	if err := decodeExtStatusRequest(info, HandshakeTypeServerHello, []byte{}); err != nil || !info.StatusRequestAck || info.OSCP {
+		t.Errorf("expected ocsp acknowledge in server hello, got: %v", err)
+	}
+}
+
+func TestExtNPN(t *testing.T) {
+	info := &ExtensionsInfo{}
+	data := []byte{0x02, 0x68, 0x32, 0x08, 0x68, 0x74, 0x74, 0x70, 0x2f, 0x31, 0x2e, 0x31}
+	if err := decodeExtNPN(info, HandshakeTypeServerHello, data); err != nil {
+		t.Fatal("decoding next_protocol_negotiation:", err)
+	}
+	if !info.NPN || len(info.NPNProtocols) != 2 || info.NPNProtocols[1] != "http/1.1" {
+		t.Errorf("unexpected npn protocols: %v", info.NPNProtocols)
+	}
+}
+
+func TestExtTrustedCAKeys(t *testing.T) {
+	data := []byte{0x00, 0x1b, byte(TrustedAuthorityPreAgreed), byte(TrustedAuthorityKeySHA1Hash)}
+	data = append(data, make([]byte, 20)...)
+	data = append(data, byte(TrustedAuthorityX509Name), 0x00, 0x02, 0x30, 0x00)
+	info := &ExtensionsInfo{}
+	if err := decodeExtTrustedCAKeys(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding trusted_ca_keys:", err)
+	}
+	if len(info.TrustedCAKeys) != 3 {
+		t.Fatalf("expected trusted_ca_keys: 3, got: %v", len(info.TrustedCAKeys))
+	}
+	if info.TrustedCAKeys[1].Type != TrustedAuthorityKeySHA1Hash || len(info.TrustedCAKeys[1].Data) != 20 {
+		t.Errorf("unexpected trusted authority: %v", info.TrustedCAKeys[1])
+	}
+	if info.TrustedCAKeys[2].Type != TrustedAuthorityX509Name || len(info.TrustedCAKeys[2].Data) != 2 {
+		t.Errorf("unexpected trusted authority: %v", info.TrustedCAKeys[2])
+	}
+}
+
+func TestHskCertificateURL(t *testing.T) {
+	url := "http://example.com/cert.der"
+	payload := []byte{0x04, 0x00, 0x00, 0x00, byte(CertChainIndividualCerts), 0x00, byte(2 + len(url) + 21), 0x00, byte(len(url))}
+	payload = append(payload, url...)
+	payload = append(payload, 0x01)
+	payload = append(payload, make([]byte, 20)...)
+	payload[0] = byte(HandshakeTypeCertificateURL)
+	payload[3] = byte(len(payload) - 4)
+
+	handshake, err := NewHandshakeFromBytes(payload)
+	if err != nil {
+		t.Fatal("getting handshake from bytes:", err)
+	}
+	if handshake.CertificateURL == nil {
+		t.Fatal("CertificateURL doesn't decoded")
+	}
+	if len(handshake.CertificateURL.URLs) != 1 || handshake.CertificateURL.URLs[0].URL != url {
+		t.Errorf("unexpected urls: %v", handshake.CertificateURL.URLs)
+	}
+}
+
+func hasAnomaly(anomalies []ExtensionAnomaly, a ExtensionAnomalyType, e ExtensionType) bool {
+	for _, an := range anomalies {
+		if an.Anomaly == a && an.Extension == e {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheckClientHelloExtensions(t *testing.T) {
+	ch := &ClientHelloData{
+		Extensions: []Extension{
+			{Type: ExtServerName}, {Type: ExtPreSharedKey}, {Type: ExtKeyShare}, {Type: ExtKeyShare},
+		},
+	}
+	anomalies := CheckClientHelloExtensions(ch)
+	if !hasAnomaly(anomalies, ExtAnomalyDuplicated, ExtKeyShare) {
+		t.Errorf("expected duplicated key_share, got: %v", anomalies)
+	}
+	if !hasAnomaly(anomalies, ExtAnomalyMisordered, ExtPreSharedKey) {
+		t.Errorf("expected misordered pre_shared_key, got: %v", anomalies)
+	}
+	if !hasAnomaly(anomalies, ExtAnomalyTLS13Only, ExtKeyShare) {
+		t.Errorf("expected tls13 only key_share, got: %v", anomalies)
+	}
+
+	ch = &ClientHelloData{
+		Extensions: []Extension{
+			{Type: ExtSupportedVersions, Payload: []byte{0x02, 0x03, 0x04}}, {Type: ExtKeyShare}, {Type: ExtPreSharedKey},
+		},
+	}
+	if anomalies := CheckClientHelloExtensions(ch); len(anomalies) != 0 {
+		t.Errorf("unexpected anomalies: %v", anomalies)
+	}
+
+	// dtls 1.2 client
+	ch = &ClientHelloData{
+		ClientVersion: tlslayer.VersionDTLS12,
+		Extensions:    []Extension{{Type: ExtKeyShare}},
+	}
+	if anomalies := CheckClientHelloExtensions(ch); !hasAnomaly(anomalies, ExtAnomalyTLS13Only, ExtKeyShare) {
+		t.Errorf("expected tls13 only key_share, got: %v", anomalies)
+	}
+	// dtls 1.3 client
+	ch = &ClientHelloData{
+		ClientVersion: tlslayer.VersionDTLS12,
+		Extensions: []Extension{
+			{Type: ExtSupportedVersions, Payload: []byte{0x04, 0xfe, 0xfc, 0xfe, 0xfd}}, {Type: ExtKeyShare},
+		},
+	}
+	if anomalies := CheckClientHelloExtensions(ch); len(anomalies) != 0 {
+		t.Errorf("unexpected anomalies: %v", anomalies)
+	}
+}
+
+func TestCheckServerHelloExtensions(t *testing.T) {
+	ch := &ClientHelloData{
+		CipherSuites: []CipherSuite{CipherSuiteEmptyRenegotiationInfoSCSV},
+		Extensions:   []Extension{{Type: ExtServerName}, {Type: ExtALPN}, {Type: ExtKeyShare}},
+	}
+	sh := &ServerHelloData{
+		Extensions: []Extension{{Type: ExtRenegotiationInfo}, {Type: ExtALPN}, {Type: ExtHeartbeat}, {Type: ExtKeyShare}},
+	}
+	anomalies := CheckServerHelloExtensions(ch, sh)
+	if len(anomalies) != 2 {
+		t.Errorf("expected anomalies: 2, got: %v", anomalies)
+	}
+	if !hasAnomaly(anomalies, ExtAnomalyUnsolicited, ExtHeartbeat) {
+		t.Errorf("expected unsolicited heartbeat, got: %v", anomalies)
+	}
+	if !hasAnomaly(anomalies, ExtAnomalyTLS13Only, ExtKeyShare) {
+		t.Errorf("expected tls13 only key_share, got: %v", anomalies)
+	}
+
+	sh = &ServerHelloData{
+		Extensions: []Extension{{Type: ExtSupportedVersions, Payload: []byte{0x03, 0x04}}, {Type: ExtKeyShare}, {Type: ExtALPN}},
+	}
+	anomalies = CheckServerHelloExtensions(ch, sh)
+	if !hasAnomaly(anomalies, ExtAnomalyForbidden, ExtALPN) {
+		t.Errorf("expected forbidden alpn, got: %v", anomalies)
+	}
+	if !hasAnomaly(anomalies, ExtAnomalyUnsolicited, ExtSupportedVersions) {
+		t.Errorf("expected unsolicited supported_versions, got: %v", anomalies)
+	}
+}
+
+func TestExtPreSharedKey(t *testing.T) {
+	data := []byte{
+		0x00, 0x0e, // identities
+		0x00, 0x04, 0xaa, 0xbb, 0xcc, 0xdd, 0x00, 0x00, 0x01, 0x00,
+		0x00, 0x00, // truncated identity
+	}
+	info := &ExtensionsInfo{}
+	if err := decodeExtPreSharedKey(info, HandshakeTypeClientHello, data); err != ErrHandshakeExtBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadLength, err)
+	}
+
+	data = []byte{0x00, 0x0a, 0x00, 0x04, 0xaa, 0xbb, 0xcc, 0xdd, 0x00, 0x00, 0x01, 0x00, 0x00, 0x21, 0x20}
+	data = append(data, make([]byte, 32)...)
+	info = &ExtensionsInfo{}
+	if err := decodeExtPreSharedKey(info, HandshakeTypeClientHello, data); err != nil {
+		t.Fatal("decoding pre_shared_key:", err)
+	}
+	if !info.PreSharedKey || len(info.PSKIdentities) != 1 || len(info.PSKBinders) != 1 {
+		t.Fatalf("unexpected psk: %v %v", info.PSKIdentities, info.PSKBinders)
+	}
+	if !bytes.Equal(info.PSKIdentities[0].Identity, []byte{0xaa, 0xbb, 0xcc, 0xdd}) || info.PSKIdentities[0].ObfuscatedTicketAge != 256 {
+		t.Errorf("unexpected identity: %v", info.PSKIdentities[0])
+	}
+	if len(info.PSKBinders[0]) != 32 {
+		t.Errorf("expected binder len: 32, got: %v", len(info.PSKBinders[0]))
+	}
+
+	info = &ExtensionsInfo{}
+	if err := decodeExtPreSharedKey(info, HandshakeTypeServerHello, []byte{0x00, 0x01}); err != nil {
+		t.Fatal("decoding pre_shared_key:", err)
+	}
+	if !info.PreSharedKey || info.PSKSelectedIdentity != 1 {
+		t.Errorf("expected selected identity: 1, got: %v", info.PSKSelectedIdentity)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import "fmt"
+
+// TokenBindingKeyParameter is a key parameter defined in rfc8472
+type TokenBindingKeyParameter uint8
+
+// TokenBindingKeyParameter possible values
+const (
+	TokenBindingRSA2048PKCS15 TokenBindingKeyParameter = 0
+	TokenBindingRSA2048PSS    TokenBindingKeyParameter = 1
+	TokenBindingECDSAP256     TokenBindingKeyParameter = 2
+)
+
+func (p TokenBindingKeyParameter) getDesc() string {
+	switch p {
+	case TokenBindingRSA2048PKCS15:
+		return "rsa2048_pkcs1.5"
+	case TokenBindingRSA2048PSS:
+		return "rsa2048_pss"
+	case TokenBindingECDSAP256:
+		return "ecdsap256"
+	default:
+		return "unknown"
+	}
+}
+
+func (p TokenBindingKeyParameter) String() string {
+	return fmt.Sprintf("%s(%d)", p.getDesc(), p)
+}
+
+func decodeExtTokenBinding(info *ExtensionsInfo, ht HandshakeType, data []byte) error {
+	if len(data) < 3 {
+		return ErrHandshakeExtBadLength
+	}
+	info.TokenBindingVersion = uint16(data[0])<<8 | uint16(data[1])
+	paramsLen := int(data[2])
+	data = data[3:]
+
+	if paramsLen == 0 || len(data) != paramsLen {
+		return ErrHandshakeExtBadLength
+	}
+	if cap(info.TokenBindingKeyParams) < paramsLen {
+		info.TokenBindingKeyParams = make([]TokenBindingKeyParameter, paramsLen)
+	}
+	info.TokenBindingKeyParams = info.TokenBindingKeyParams[:paramsLen]
+	for i := 0; i < paramsLen; i++ {
+		info.TokenBindingKeyParams[i] = TokenBindingKeyParameter(data[i])
+	}
+
+	return nil
+}
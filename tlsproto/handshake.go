@@ -8,6 +8,8 @@ import (
 	"github.com/luisguillenc/tlslayer"
 )
 
+// DecodeExtensions is the default value of DecodeOptions.DecodeExtensions,
+// it's only used by functions without options
 var DecodeExtensions bool = true
 
 // HandshakeType defines the type of handshake
@@ -18,6 +20,7 @@ const (
 	HandshakeTypeHelloRequest       HandshakeType = 0
 	HandshakeTypeClientHello        HandshakeType = 1
 	HandshakeTypeServerHello        HandshakeType = 2
+	HandshakeTypeHelloVerifyRequest HandshakeType = 3
 	HandshakeTypeNewSessionTicket   HandshakeType = 4
 	HandshakeTypeEndOfEarlyData     HandshakeType = 5
 	HandshakeTypeCertificate        HandshakeType = 11
@@ -33,7 +36,7 @@ const (
 )
 
 // decodeHskMsg is a function prototype that decodes handshake messages
-type decodeHskMsg func(hsk *Handshake, data []byte) error
+type decodeHskMsg func(hsk *Handshake, data []byte, opts *DecodeOptions) error
 
 // HandShakeTypeReg is a map with strings of alert description
 var handShakeTypeReg = map[HandshakeType]struct {
@@ -43,7 +46,8 @@ var handShakeTypeReg = map[HandshakeType]struct {
 	HandshakeTypeHelloRequest:       {"hello_request", nil},
 	HandshakeTypeClientHello:        {"client_hello", decodeHskClientHello},
 	HandshakeTypeServerHello:        {"server_hello", decodeHskServerHello},
-	HandshakeTypeNewSessionTicket:   {"new_session_ticket", nil},
+	HandshakeTypeHelloVerifyRequest: {"hello_verify_request", decodeHskHelloVerifyRequest},
+	HandshakeTypeNewSessionTicket:   {"new_session_ticket", decodeHskNewSessionTicket},
 	HandshakeTypeEndOfEarlyData:     {"end_of_early_data", nil},
 	HandshakeTypeCertificate:        {"certificate", decodeHskCertificate},
 	HandshakeTypeServerKeyExchange:  {"server_key_exchange", nil},
@@ -52,7 +56,7 @@ var handShakeTypeReg = map[HandshakeType]struct {
 	HandshakeTypeCertificateVerify:  {"certificate_verify", nil},
 	HandshakeTypeClientKeyExchange:  {"client_key_exchange", nil},
 	HandshakeTypeFinished:           {"finished", nil},
-	HandshakeTypeCertificateURL:     {"certificate_url", nil},
+	HandshakeTypeCertificateURL:     {"certificate_url", decodeHskCertificateURL},
 	HandshakeTypeCertificateStatus:  {"certificate_status", nil},
 	HandshakeTypeKeyUpdate:          {"key_update", nil},
 }
@@ -84,6 +88,57 @@ type Handshake struct {
 	ClientHello *ClientHelloData `json:"clientHello,omitempty"`
 	ServerHello *ServerHelloData `json:"serverHello,omitempty"`
 	Certificate *CertificateData `json:"certificate,omitempty"`
+
+	CertificateURL   *CertificateURLData   `json:"certificateURL,omitempty"`
+	NewSessionTicket *NewSessionTicketData `json:"newSessionTicket,omitempty"`
+
+	HelloVerifyRequest *HelloVerifyRequestData `json:"helloVerifyRequest,omitempty"`
+	// MessageSeq is the sequence number of the message in dtls
+	MessageSeq uint16 `json:"messageSeq,omitempty"`
+
+	// buffers kept between decodes when the struct is reused
+	clientHelloBuf *ClientHelloData
+	serverHelloBuf *ServerHelloData
+	certificateBuf *CertificateData
+}
+
+// reset clears the handshake keeping buffers, so it can be reused without allocations
+func (hs *Handshake) reset() {
+	*hs = Handshake{
+		clientHelloBuf: hs.clientHelloBuf,
+		serverHelloBuf: hs.serverHelloBuf,
+		certificateBuf: hs.certificateBuf,
+	}
+}
+
+// newClientHello returns an empty ClientHelloData reusing the buffer if it exists
+func (hs *Handshake) newClientHello() *ClientHelloData {
+	if hs.clientHelloBuf == nil {
+		hs.clientHelloBuf = &ClientHelloData{}
+	} else {
+		hs.clientHelloBuf.reset()
+	}
+	return hs.clientHelloBuf
+}
+
+// newServerHello returns an empty ServerHelloData reusing the buffer if it exists
+func (hs *Handshake) newServerHello() *ServerHelloData {
+	if hs.serverHelloBuf == nil {
+		hs.serverHelloBuf = &ServerHelloData{}
+	} else {
+		hs.serverHelloBuf.reset()
+	}
+	return hs.serverHelloBuf
+}
+
+// newCertificate returns an empty CertificateData reusing the buffer if it exists
+func (hs *Handshake) newCertificate() *CertificateData {
+	if hs.certificateBuf == nil {
+		hs.certificateBuf = &CertificateData{}
+	} else {
+		hs.certificateBuf.reset()
+	}
+	return hs.certificateBuf
 }
 
 func (hs *Handshake) String() string {
@@ -127,53 +182,99 @@ func ReadHandshakeHeader(bytes []byte) (HandshakeType, uint32, error) {
 
 // NewHandshakeFromBytes creates a handshake from a byte slice with the payload
 func NewHandshakeFromBytes(payload []byte) (*Handshake, error) {
-	htype, hlen, err := ReadHandshakeHeader(payload)
+	return NewHandshakeFromBytesWithOptions(payload, DefaultDecodeOptions())
+}
+
+// NewHandshakeFromBytesWithOptions creates a handshake from a byte slice with the payload using the options
+func NewHandshakeFromBytesWithOptions(payload []byte, opts DecodeOptions) (*Handshake, error) {
+	if opts.CopyBuffers {
+		payload = append([]byte(nil), payload...)
+	}
+	return newHandshakeFromBytes(payload, &opts)
+}
+
+func newHandshakeFromBytes(payload []byte, opts *DecodeOptions) (*Handshake, error) {
+	htype, hlen, err := checkHandshakeHeader(payload, opts)
 	if err != nil {
 		return nil, err
 	}
+	// creates handshake
+	handshake := &Handshake{}
+	err = handshake.decode(htype, hlen, payload[4:], opts)
+	return handshake, err
+}
+
+// checkHandshakeHeader reads the header and checks the length of the message
+func checkHandshakeHeader(payload []byte, opts *DecodeOptions) (HandshakeType, uint32, error) {
+	htype, hlen, err := ReadHandshakeHeader(payload)
+	if err != nil {
+		return 0, 0, err
+	}
+	if opts.MaxHandshakeSize > 0 && hlen > opts.MaxHandshakeSize {
+		return 0, 0, ErrHandshakeTooLarge
+	}
 	// check if payload is completed
 	if int(hlen) != len(payload)-4 {
-		return nil, ErrHandshakePayloadMissmatch
+		return 0, 0, ErrHandshakePayloadMissmatch
 	}
-	// creates handshake
-	handshake := &Handshake{}
-	handshake.Type = htype
-	handshake.Len = hlen
-	// decode payload
-	hskpayload := payload[4:]
+	return htype, hlen, nil
+}
+
+// decode sets the header values and decodes the payload of the message
+func (hs *Handshake) decode(htype HandshakeType, hlen uint32, hskpayload []byte, opts *DecodeOptions) error {
+	hs.Type = htype
+	hs.Len = hlen
 	h, _ := handShakeTypeReg[htype]
 	if h.decoder != nil {
-		err = h.decoder(handshake, hskpayload)
+		return h.decoder(hs, hskpayload, opts)
 	}
-	return handshake, err
+	return nil
 }
 
 // NewHandshakesFromRecord creates a slice with handshakes from a byte slice with the payload
 func NewHandshakesFromRecord(tlsr *tlslayer.TLSRecord) ([]*Handshake, error) {
+	return NewHandshakesFromRecordWithOptions(tlsr, DefaultDecodeOptions())
+}
+
+// NewHandshakesFromRecordWithOptions creates a slice with handshakes from a tls record using the options.
+// In lenient mode the handshakes decoded are returned with the first error found.
+func NewHandshakesFromRecordWithOptions(tlsr *tlslayer.TLSRecord, opts DecodeOptions) ([]*Handshake, error) {
 	if tlsr.Type != tlslayer.ContentTypeHandshake {
 		return nil, ErrUnexpectedRecordType
 	}
 	// manages multiple handshakes messages in a tls record
 	payload := tlsr.Payload()
+	if opts.CopyBuffers {
+		payload = append([]byte(nil), payload...)
+	}
 	handshakes := make([]*Handshake, 0)
+	var firstErr error
 	for len(payload) > 0 {
 		_, hlen, err := ReadHandshakeHeader(payload)
+		if err == nil && int(hlen) > len(payload)-4 {
+			// handshake is fragmented
+			err = ErrHandshakeFragmented
+		}
 		if err != nil {
+			if opts.Lenient {
+				return handshakes, err
+			}
 			return nil, err
 		}
-		if int(hlen) > len(payload)-4 {
-			// handshake is fragmented
-			return nil, ErrHandshakeFragmented
-		}
 		bytes := payload[:hlen+4]
-		handshake, err := NewHandshakeFromBytes(bytes)
+		handshake, err := newHandshakeFromBytes(bytes, &opts)
 		if err != nil {
-			return nil, err
+			if !opts.Lenient || handshake == nil {
+				return nil, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
 		}
 		handshakes = append(handshakes, handshake)
 
 		// next handshake
 		payload = payload[hlen+4:]
 	}
-	return handshakes, nil
+	return handshakes, firstErr
 }
@@ -3,29 +3,97 @@
 package tlsproto
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 )
 
+// Fingerprint is the hash of a DER encoded certificate
+type Fingerprint []byte
+
+func (f Fingerprint) String() string {
+	return hex.EncodeToString(f)
+}
+
+// MarshalText encodes fingerprint in hexadecimal
+func (f Fingerprint) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// CertificateEntry stores a DER encoded certificate of the chain and its fingerprints
+type CertificateEntry struct {
+	Raw    []byte      `json:"-"`
+	SHA1   Fingerprint `json:"sha1"`
+	SHA256 Fingerprint `json:"sha256"`
+
+	cache *CertificateCache
+}
+
+// Certificate returns the parsed certificate, results are cached by fingerprint
+// in the cache of the options used to decode the entry
+func (e *CertificateEntry) Certificate() (*x509.Certificate, error) {
+	return e.cache.Parse(e.SHA256, e.Raw)
+}
+
 // CertificateData is the struct for protocol hanshake message Certificate
 type CertificateData struct {
 	CertificatesLen uint32              `json:"certificatesLen"`
 	Certificates    []*x509.Certificate `json:"certificates,omitempty"`
+	// Entries has the raw certificates, Certificates is empty until Parse
+	// is called if they were decoded with LazyCertificates option
+	Entries []CertificateEntry `json:"entries,omitempty"`
+
+	parsed bool
 }
 
 func (hs *CertificateData) String() string {
 	str := fmt.Sprintln("Certificates Len:", hs.CertificatesLen)
+	for _, e := range hs.Entries {
+		str += fmt.Sprintln("SHA1 Fingerprint:", e.SHA1)
+	}
 
 	return str
 }
 
-func decodeHskCertificate(hsk *Handshake, payload []byte) error {
+// reset clears the struct keeping buffers, so it can be reused without allocations
+func (hs *CertificateData) reset() {
+	*hs = CertificateData{
+		Certificates: hs.Certificates[:0],
+		Entries:      hs.Entries[:0],
+	}
+}
+
+// Parse parses the certificates of the chain if they weren't and returns them
+func (hs *CertificateData) Parse() ([]*x509.Certificate, error) {
+	if hs.parsed {
+		return hs.Certificates, nil
+	}
+	certs := make([]*x509.Certificate, 0, len(hs.Entries))
+	for i := range hs.Entries {
+		cert, err := hs.Entries[i].Certificate()
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	hs.Certificates = certs
+	hs.parsed = true
+	return certs, nil
+}
+
+func decodeHskCertificate(hsk *Handshake, payload []byte, opts *DecodeOptions) error {
 	// Get certificateslen
 	if len(payload) < 3 {
 		return ErrCertsBadLength
 	}
 	// new certdata
-	certData := &CertificateData{}
+	certData := hsk.newCertificate()
+	if opts.Lenient {
+		// partial data is returned on error
+		hsk.Certificate = certData
+	}
 	certData.CertificatesLen = uint32(payload[0])<<16 | uint32(payload[1])<<8 | uint32(payload[2])
 	payload = payload[3:]
 
@@ -34,21 +102,49 @@ func decodeHskCertificate(hsk *Handshake, payload []byte) error {
 		return ErrCertsMissmatch
 	}
 	// get certificates
+	var parseErr error
 	for len(payload) > 0 {
+		if len(payload) < 3 {
+			return ErrCertsBadLength
+		}
 		certLen := uint32(payload[0])<<16 | uint32(payload[1])<<8 | uint32(payload[2])
-		if len(payload) < int(certLen) {
+		if len(payload)-3 < int(certLen) {
 			return ErrCertsInvalidPayload
 		}
 		certificate := payload[3 : 3+certLen]
-		asnCert, err := x509.ParseCertificate(certificate)
-		if err != nil {
-			return err
+		sum1 := sha1.Sum(certificate)
+		sum256 := sha256.Sum256(certificate)
+		// fingerprint buffers of a reused entry are overwritten
+		var entry CertificateEntry
+		if len(certData.Entries) < cap(certData.Entries) {
+			entry = certData.Entries[:len(certData.Entries)+1][len(certData.Entries)]
+		}
+		entry.Raw = certificate
+		entry.SHA1 = append(entry.SHA1[:0], sum1[:]...)
+		entry.SHA256 = append(entry.SHA256[:0], sum256[:]...)
+		entry.cache = opts.CertificateCache
+		certData.Entries = append(certData.Entries, entry)
+
+		if opts.ParseCertificates && !opts.LazyCertificates {
+			asnCert, err := entry.Certificate()
+			if err != nil {
+				if !opts.Lenient {
+					return err
+				}
+				// skip certificate and continue with the chain
+				if parseErr == nil {
+					parseErr = err
+				}
+			} else {
+				certData.Certificates = append(certData.Certificates, asnCert)
+			}
 		}
-		certData.Certificates = append(certData.Certificates, asnCert)
 		// next certificate
 		payload = payload[3+certLen:]
 	}
 
+	// certificates skipped in lenient mode are parsed again by Parse
+	certData.parsed = opts.ParseCertificates && !opts.LazyCertificates && parseErr == nil
 	hsk.Certificate = certData
-	return nil
+	return parseErr
 }
@@ -0,0 +1,90 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// CertChainType is the type of chain in a certificate url message defined in rfc6066
+type CertChainType uint8
+
+// CertChainType possible values
+const (
+	CertChainIndividualCerts CertChainType = 0
+	CertChainPkiPath         CertChainType = 1
+)
+
+func (c CertChainType) getDesc() string {
+	switch c {
+	case CertChainIndividualCerts:
+		return "individual_certs"
+	case CertChainPkiPath:
+		return "pkipath"
+	default:
+		return "unknown"
+	}
+}
+
+func (c CertChainType) String() string {
+	return fmt.Sprintf("%s(%d)", c.getDesc(), c)
+}
+
+// URLAndHash stores an url and the sha1 hash of the certificate
+type URLAndHash struct {
+	URL  string `json:"url"`
+	Hash []byte `json:"hash,omitempty"`
+}
+
+// CertificateURLData is the struct for protocol handshake message CertificateURL
+type CertificateURLData struct {
+	ChainType CertChainType `json:"chainType"`
+	URLs      []URLAndHash  `json:"urls,omitempty"`
+}
+
+func (hs *CertificateURLData) String() string {
+	str := fmt.Sprintln("Chain Type:", hs.ChainType)
+	for _, u := range hs.URLs {
+		str += fmt.Sprintf("URL: %s (hash=%x)\n", u.URL, u.Hash)
+	}
+
+	return str
+}
+
+func decodeHskCertificateURL(hsk *Handshake, payload []byte, opts *DecodeOptions) error {
+	if len(payload) < 3 {
+		return ErrHandshakeBadLength
+	}
+	urlData := &CertificateURLData{}
+	urlData.ChainType = CertChainType(payload[0])
+	listLen := int(payload[1])<<8 | int(payload[2])
+	payload = payload[3:]
+
+	if len(payload) != listLen {
+		return ErrHandshakeBadLength
+	}
+	for len(payload) > 0 {
+		if len(payload) < 2 {
+			return ErrHandshakeBadLength
+		}
+		urlLen := int(payload[0])<<8 | int(payload[1])
+		payload = payload[2:]
+		// url, padding and hash
+		if urlLen == 0 || len(payload) < urlLen+1+sha1.Size {
+			return ErrHandshakeBadLength
+		}
+		entry := URLAndHash{URL: string(payload[:urlLen])}
+		payload = payload[urlLen:]
+		if payload[0] != 1 {
+			return ErrHandshakeBadLength
+		}
+		entry.Hash = payload[1 : 1+sha1.Size]
+		payload = payload[1+sha1.Size:]
+
+		urlData.URLs = append(urlData.URLs, entry)
+	}
+
+	hsk.CertificateURL = urlData
+	return nil
+}
@@ -14,20 +14,48 @@ const (
 
 // ClientHelloData stores data from a clienthello handshake
 type ClientHelloData struct {
-	ClientVersion   tlslayer.ProtocolVersion `json:"clientVersion"`
-	Random          []byte                   `json:"random,omitempty"`
-	SessionID       []byte                   `json:"sessionID,omitempty"`
-	CipherSuites    []CipherSuite            `json:"cipherSuites,omitempty"`
-	CompressMethods []CompressionMethod      `json:"compressMethods,omitempty"`
+	ClientVersion tlslayer.ProtocolVersion `json:"clientVersion"`
+	Random        []byte                   `json:"random,omitempty"`
+	SessionID     []byte                   `json:"sessionID,omitempty"`
+	// Cookie is only present in dtls, it's the value sent by server in hello verify request
+	Cookie          []byte              `json:"cookie,omitempty"`
+	CipherSuites    []CipherSuite       `json:"cipherSuites,omitempty"`
+	CompressMethods []CompressionMethod `json:"compressMethods,omitempty"`
 
 	ExtensionsLen uint16          `json:"extensionsLen"`
 	Extensions    []Extension     `json:"extensions,omitempty"`
 	ExtInfo       *ExtensionsInfo `json:"extInfo,omitempty"`
+
+	// extInfoBuf is kept between decodes when the struct is reused
+	extInfoBuf *ExtensionsInfo
+}
+
+// reset clears the struct keeping buffers, so it can be reused without allocations
+func (ch *ClientHelloData) reset() {
+	*ch = ClientHelloData{
+		CipherSuites:    ch.CipherSuites[:0],
+		CompressMethods: ch.CompressMethods[:0],
+		Extensions:      ch.Extensions[:0],
+		extInfoBuf:      ch.extInfoBuf,
+	}
+}
+
+// newExtInfo returns an empty ExtensionsInfo reusing the buffer if it exists
+func (ch *ClientHelloData) newExtInfo() *ExtensionsInfo {
+	if ch.extInfoBuf == nil {
+		ch.extInfoBuf = &ExtensionsInfo{}
+	} else {
+		ch.extInfoBuf.reset()
+	}
+	return ch.extInfoBuf
 }
 
 func (ch *ClientHelloData) String() string {
 	str := fmt.Sprintln("Version:", ch.ClientVersion)
 	str += fmt.Sprintf("SessionID: %#v\n", ch.SessionID)
+	if ch.ClientVersion.IsDTLS() {
+		str += fmt.Sprintf("Cookie: %#v\n", ch.Cookie)
+	}
 	str += fmt.Sprintf("Cipher Suites: %v\n", ch.CipherSuites)
 	str += fmt.Sprintf("Compression Methods: %v\n", ch.CompressMethods)
 	str += fmt.Sprintf("Extensions: %v\n", ch.Extensions)
@@ -74,11 +102,56 @@ func (ch *ClientHelloData) UseGREASE() bool {
 	return false
 }
 
-func decodeHskClientHello(hsk *Handshake, payload []byte) error {
+// FallbackSCSV returns true if client sends the signaling cipher suite of a
+// connection retried with a lower version than it supports (rfc7507)
+func (ch *ClientHelloData) FallbackSCSV() bool {
+	for _, c := range ch.CipherSuites {
+		if c == CipherSuiteFallbackSCSV {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxOfferedVersion returns the highest version offered by client, considering
+// supported_versions extension. GREASE values are ignored, drafts are TLS 1.3
+// and DTLS versions are ordered by the TLS version they are based on
+func (ch *ClientHelloData) MaxOfferedVersion() tlslayer.ProtocolVersion {
+	max := ch.ClientVersion
+	for _, sv := range getSupportedVersions(HandshakeTypeClientHello, ch.Extensions, ch.ExtInfo) {
+		if sv.IsGREASE() {
+			continue
+		}
+		if v := sv.Version(); max.Less(v) {
+			max = v
+		}
+	}
+	return max
+}
+
+// SecureRenegotiation returns true if client supports secure renegotiation
+// by sending the renegotiation_info extension or the signaling cipher suite
+func (ch *ClientHelloData) SecureRenegotiation() bool {
+	for _, c := range ch.CipherSuites {
+		if c == CipherSuiteEmptyRenegotiationInfoSCSV {
+			return true
+		}
+	}
+	if ch.ExtInfo != nil {
+		return ch.ExtInfo.RenegotiationInfo
+	}
+	return false
+}
+
+func decodeHskClientHello(hsk *Handshake, payload []byte, opts *DecodeOptions) error {
 	if len(payload) < 2 {
 		return ErrHandshakeBadLength
 	}
-	helloData := &ClientHelloData{}
+	helloData := hsk.newClientHello()
+	if opts.Lenient {
+		// partial data is returned on error
+		hsk.ClientHello = helloData
+	}
 	// Get client version
 	helloData.ClientVersion = tlslayer.ProtocolVersion(uint16(payload[0])<<8 | uint16(payload[1]))
 	payload = payload[2:]
@@ -106,6 +179,21 @@ func decodeHskClientHello(hsk *Handshake, payload []byte) error {
 	}
 	payload = payload[sessionIDLen:]
 
+	// Get Cookie in dtls
+	if helloData.ClientVersion.IsDTLS() {
+		if len(payload) < 1 {
+			return ErrHandshakeBadLength
+		}
+		cookieLen := int(payload[0])
+		if len(payload) < 1+cookieLen {
+			return ErrHandshakeBadLength
+		}
+		if cookieLen != 0 {
+			helloData.Cookie = payload[1 : 1+cookieLen]
+		}
+		payload = payload[1+cookieLen:]
+	}
+
 	// Get CipherSuites
 	if len(payload) < 2 {
 		return ErrHandshakeBadLength
@@ -113,10 +201,13 @@ func decodeHskClientHello(hsk *Handshake, payload []byte) error {
 	cipherSuiteLen := uint16(payload[0])<<8 | uint16(payload[1])
 	numCiphers := cipherSuiteLen / 2
 
-	if len(payload) < int(cipherSuiteLen) {
+	if len(payload) < 2+int(cipherSuiteLen) {
 		return ErrHandshakeBadLength
 	}
-	helloData.CipherSuites = make([]CipherSuite, numCiphers)
+	if cap(helloData.CipherSuites) < int(numCiphers) {
+		helloData.CipherSuites = make([]CipherSuite, numCiphers)
+	}
+	helloData.CipherSuites = helloData.CipherSuites[:numCiphers]
 	for i := 0; i < int(numCiphers); i++ {
 		helloData.CipherSuites[i] = CipherSuite(payload[2+2*i])<<8 | CipherSuite(payload[3+2*i])
 	}
@@ -130,7 +221,10 @@ func decodeHskClientHello(hsk *Handshake, payload []byte) error {
 	if len(payload) < 1+numCompressMethods {
 		return ErrHandshakeBadLength
 	}
-	helloData.CompressMethods = make([]CompressionMethod, numCompressMethods)
+	if cap(helloData.CompressMethods) < numCompressMethods {
+		helloData.CompressMethods = make([]CompressionMethod, numCompressMethods)
+	}
+	helloData.CompressMethods = helloData.CompressMethods[:numCompressMethods]
 	for i := 0; i < int(numCompressMethods); i++ {
 		helloData.CompressMethods[i] = CompressionMethod(payload[1+1*i])
 	}
@@ -153,15 +247,19 @@ func decodeHskClientHello(hsk *Handshake, payload []byte) error {
 		return ErrHandshakeExtBadLength
 	}
 	var err error
-	helloData.Extensions, err = getExtensionsFromBytes(payload)
-	if err != nil {
+	if helloData.Extensions == nil {
+		helloData.Extensions = make([]Extension, 0, extensionsCap)
+	}
+	helloData.Extensions, err = appendExtensionsFromBytes(helloData.Extensions, payload, opts.MaxExtensions)
+	if err != nil && !opts.Lenient {
 		return err
 	}
-	if DecodeExtensions {
-		helloData.ExtInfo, err = getExtensionsInfo(HandshakeTypeClientHello, helloData.Extensions)
-		if err != nil {
-			return err
-		}
+	if opts.DecodeExtensions {
+		helloData.ExtInfo = helloData.newExtInfo()
+		decodeExtensionsInfo(helloData.ExtInfo, HandshakeTypeClientHello, helloData.Extensions)
+	}
+	if err != nil {
+		return err
 	}
 	hsk.ClientHello = helloData
 	return nil
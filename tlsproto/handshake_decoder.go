@@ -0,0 +1,137 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"github.com/google/gopacket"
+	"github.com/luisguillenc/tlslayer"
+)
+
+// HandshakeDecoder decodes the handshake messages of a record payload reusing
+// its memory between calls, so decoded data is only valid until next decode.
+// It satisfaces gopacket.DecodingLayer.
+type HandshakeDecoder struct {
+	Options DecodeOptions
+	// Handshakes are the messages decoded from the last payload, if an error
+	// is returned it stores the messages decoded until the error
+	Handshakes []Handshake
+
+	contents []byte
+	buf      []byte
+}
+
+// NewHandshakeDecoder returns a decoder that uses the options
+func NewHandshakeDecoder(opts DecodeOptions) *HandshakeDecoder {
+	return &HandshakeDecoder{Options: opts}
+}
+
+// Reset clears the decoded messages keeping the memory allocated
+func (d *HandshakeDecoder) Reset() {
+	d.Handshakes = d.Handshakes[:0]
+	d.contents = nil
+}
+
+// next returns an empty handshake reusing the memory of previous decodes
+func (d *HandshakeDecoder) next() *Handshake {
+	n := len(d.Handshakes)
+	if n < cap(d.Handshakes) {
+		d.Handshakes = d.Handshakes[:n+1]
+		d.Handshakes[n].reset()
+	} else {
+		d.Handshakes = append(d.Handshakes, Handshake{})
+	}
+	return &d.Handshakes[n]
+}
+
+// DecodeFromBytes decodes all the handshake messages of the byte slice.
+// In lenient mode the messages are decoded and the first error is returned.
+func (d *HandshakeDecoder) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	d.Reset()
+	if d.Options.CopyBuffers {
+		d.buf = append(d.buf[:0], data...)
+		data = d.buf
+	}
+	d.contents = data
+
+	var firstErr error
+	for len(data) > 0 {
+		_, hlen, err := ReadHandshakeHeader(data)
+		if err == nil && int(hlen) > len(data)-4 {
+			// handshake is fragmented
+			if df != nil {
+				df.SetTruncated()
+			}
+			err = ErrHandshakeFragmented
+		}
+		if err != nil {
+			if firstErr != nil {
+				return firstErr
+			}
+			return err
+		}
+		msg := data[:hlen+4]
+		htype, hlen, err := checkHandshakeHeader(msg, &d.Options)
+		if err != nil {
+			return err
+		}
+		hsk := d.next()
+		err = hsk.decode(htype, hlen, msg[4:], &d.Options)
+		if err != nil {
+			if !d.Options.Lenient {
+				d.Handshakes = d.Handshakes[:len(d.Handshakes)-1]
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		// next handshake
+		data = data[hlen+4:]
+	}
+	return firstErr
+}
+
+// DecodeRecord decodes the handshake messages of a tls record
+func (d *HandshakeDecoder) DecodeRecord(tlsr *tlslayer.TLSRecord) error {
+	if tlsr.Type != tlslayer.ContentTypeHandshake {
+		return ErrUnexpectedRecordType
+	}
+	return d.DecodeFromBytes(tlsr.Payload(), gopacket.NilDecodeFeedback)
+}
+
+// CanDecode satisfaces the interface
+func (d *HandshakeDecoder) CanDecode() gopacket.LayerClass {
+	return LayerTypeHandshake
+}
+
+// LayerType satisfaces the interface
+func (d *HandshakeDecoder) LayerType() gopacket.LayerType {
+	return LayerTypeHandshake
+}
+
+// NextLayerType satisfaces the interface, handshake is the last layer
+func (d *HandshakeDecoder) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+// LayerContents satisfaces the interface
+func (d *HandshakeDecoder) LayerContents() []byte {
+	return d.contents
+}
+
+// LayerPayload satisfaces the interface, handshake messages have no payload
+func (d *HandshakeDecoder) LayerPayload() []byte {
+	return nil
+}
+
+// decodeHandshakeLayer decodes the byte slice and add handshake layer to packet builder
+func decodeHandshakeLayer(data []byte, p gopacket.PacketBuilder) error {
+	d := NewHandshakeDecoder(DefaultDecodeOptions())
+	err := d.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(d)
+
+	return nil
+}
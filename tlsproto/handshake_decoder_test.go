@@ -0,0 +1,145 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlsproto
+
+import (
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/luisguillenc/tlslayer"
+)
+
+func decodeTestRecord(data []byte, t testing.TB) *tlslayer.TLSRecord {
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	return tlsrecord
+}
+
+func TestHandshakeDecoder(t *testing.T) {
+	decoder := NewHandshakeDecoder(DefaultDecodeOptions())
+
+	err := decoder.DecodeRecord(decodeTestRecord(testRecordMultipleHsk1, t))
+	if err != nil {
+		t.Fatal("decoding multiple handshakes:", err)
+	}
+	if len(decoder.Handshakes) != 3 {
+		t.Fatalf("expected handshakes: 3, got: %v", len(decoder.Handshakes))
+	}
+	if decoder.Handshakes[0].ServerHello == nil || decoder.Handshakes[1].Certificate == nil {
+		t.Error("expected serverhello and certificate decoded")
+	}
+
+	// reuse decoder with a clienthello
+	err = decoder.DecodeRecord(decodeTestRecord(testRecordClientHello1, t))
+	if err != nil {
+		t.Fatal("decoding clienthello:", err)
+	}
+	if len(decoder.Handshakes) != 1 {
+		t.Fatalf("expected handshakes: 1, got: %v", len(decoder.Handshakes))
+	}
+	hsk := decoder.Handshakes[0]
+	if hsk.ServerHello != nil || hsk.Certificate != nil {
+		t.Error("unexpected data from previous decode")
+	}
+	want, _ := NewHandshakesFromRecord(decodeTestRecord(testRecordClientHello1, t))
+	if hsk.ClientHello == nil || hsk.ClientHello.String() != want[0].ClientHello.String() {
+		t.Errorf("expected clienthello: %v, got: %v", want[0].ClientHello, hsk.ClientHello)
+	}
+
+	// decode into same memory
+	ch := hsk.ClientHello
+	err = decoder.DecodeRecord(decodeTestRecord(testRecordGREASE1, t))
+	if err != nil {
+		t.Fatal("decoding clienthello:", err)
+	}
+	if decoder.Handshakes[0].ClientHello != ch {
+		t.Error("expected clienthello struct reused")
+	}
+	if !ch.UseGREASE() {
+		t.Error("expected data from last decode")
+	}
+
+	// truncated message
+	payload := decodeTestRecord(testRecordClientHello1, t).Payload()
+	if err := decoder.DecodeFromBytes(payload[:100], gopacket.NilDecodeFeedback); err != ErrHandshakeFragmented {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeFragmented, err)
+	}
+	if len(decoder.Handshakes) != 0 {
+		t.Errorf("unexpected handshakes: %v", len(decoder.Handshakes))
+	}
+}
+
+func TestHandshakeDecoderAllocs(t *testing.T) {
+	opts := DefaultDecodeOptions()
+	opts.DecodeExtensions = false
+	decoder := NewHandshakeDecoder(opts)
+	payload := decodeTestRecord(testRecordClientHello1, t).Payload()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		decoder.DecodeFromBytes(payload, gopacket.NilDecodeFeedback)
+	})
+	if allocs != 0 {
+		t.Errorf("expected allocs: 0, got: %v", allocs)
+	}
+
+	// decoding extensions only allocates the strings of sni and alpn
+	decoder = NewHandshakeDecoder(DefaultDecodeOptions())
+	allocs = testing.AllocsPerRun(100, func() {
+		decoder.DecodeFromBytes(payload, gopacket.NilDecodeFeedback)
+	})
+	info := decoder.Handshakes[0].ClientHello.ExtInfo
+	if info.SNI == "" || info.StatusRequest == nil {
+		t.Fatal("expected sni and status_request decoded")
+	}
+	if want := float64(1 + len(info.ALPNs)); allocs != want {
+		t.Errorf("expected allocs: %v, got: %v", want, allocs)
+	}
+}
+
+func benchmarkNewHandshakes(data []byte, b *testing.B) {
+	tlsrecord := decodeTestRecord(data, b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewHandshakesFromRecord(tlsrecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkHandshakeDecoder(data []byte, b *testing.B) {
+	tlsrecord := decodeTestRecord(data, b)
+	decoder := NewHandshakeDecoder(DefaultDecodeOptions())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := decoder.DecodeRecord(tlsrecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewHandshakesClientHello(b *testing.B) {
+	benchmarkNewHandshakes(testRecordClientHello1, b)
+}
+
+func BenchmarkHandshakeDecoderClientHello(b *testing.B) {
+	benchmarkHandshakeDecoder(testRecordClientHello1, b)
+}
+
+func BenchmarkNewHandshakesServerHello(b *testing.B) {
+	benchmarkNewHandshakes(testRecordServerHello1, b)
+}
+
+func BenchmarkHandshakeDecoderServerHello(b *testing.B) {
+	benchmarkHandshakeDecoder(testRecordServerHello1, b)
+}
+
+func BenchmarkNewHandshakesMultiple(b *testing.B) {
+	benchmarkNewHandshakes(testRecordMultipleHsk1, b)
+}
+
+func BenchmarkHandshakeDecoderMultiple(b *testing.B) {
+	benchmarkHandshakeDecoder(testRecordMultipleHsk1, b)
+}
@@ -0,0 +1,41 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"fmt"
+
+	"github.com/luisguillenc/tlslayer"
+)
+
+// HelloVerifyRequestData stores data from a dtls hello verify request (rfc6347),
+// client must send again its client hello with the cookie
+type HelloVerifyRequestData struct {
+	ServerVersion tlslayer.ProtocolVersion `json:"serverVersion"`
+	Cookie        []byte                   `json:"cookie,omitempty"`
+}
+
+func (hs *HelloVerifyRequestData) String() string {
+	str := fmt.Sprintln("Version:", hs.ServerVersion)
+	str += fmt.Sprintf("Cookie: %#v\n", hs.Cookie)
+
+	return str
+}
+
+func decodeHskHelloVerifyRequest(hsk *Handshake, payload []byte, opts *DecodeOptions) error {
+	if len(payload) < 3 {
+		return ErrHandshakeBadLength
+	}
+	verifyData := &HelloVerifyRequestData{}
+	verifyData.ServerVersion = tlslayer.ProtocolVersion(uint16(payload[0])<<8 | uint16(payload[1]))
+	cookieLen := int(payload[2])
+	if len(payload) != 3+cookieLen {
+		return ErrHandshakeBadLength
+	}
+	if cookieLen != 0 {
+		verifyData.Cookie = payload[3:]
+	}
+
+	hsk.HelloVerifyRequest = verifyData
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"fmt"
+)
+
+// NewSessionTicketData stores data from a NewSessionTicket message. Its format
+// is different in TLS 1.2 (rfc5077) and TLS 1.3 (rfc8446), the version is
+// deduced from the length of the message. In TLS 1.3 messages are only visible
+// if the handshake is decrypted.
+type NewSessionTicketData struct {
+	// TLS13 is true if message has the format of TLS 1.3
+	TLS13 bool `json:"tls13"`
+	// Lifetime is ticket_lifetime_hint in TLS 1.2
+	Lifetime uint32 `json:"lifetime"`
+	AgeAdd   uint32 `json:"ageAdd,omitempty"`
+	Nonce    []byte `json:"nonce,omitempty"`
+	Ticket   []byte `json:"ticket,omitempty"`
+
+	Extensions []Extension     `json:"extensions,omitempty"`
+	ExtInfo    *ExtensionsInfo `json:"extInfo,omitempty"`
+}
+
+func (hs *NewSessionTicketData) String() string {
+	str := fmt.Sprintln("TLS 1.3:", hs.TLS13)
+	str += fmt.Sprintln("Lifetime:", hs.Lifetime)
+	str += fmt.Sprintln("Age Add:", hs.AgeAdd)
+	str += fmt.Sprintf("Nonce: %x\n", hs.Nonce)
+	str += fmt.Sprintf("Ticket: (len=%d)\n", len(hs.Ticket))
+	str += fmt.Sprintln("Extensions:", hs.Extensions)
+
+	return str
+}
+
+func decodeHskNewSessionTicket(hsk *Handshake, payload []byte, opts *DecodeOptions) error {
+	if len(payload) < 6 {
+		return ErrHandshakeBadLength
+	}
+	ticketData := &NewSessionTicketData{}
+	ticketData.Lifetime = uint32(payload[0])<<24 | uint32(payload[1])<<16 | uint32(payload[2])<<8 | uint32(payload[3])
+	payload = payload[4:]
+
+	ticketLen := int(payload[0])<<8 | int(payload[1])
+	if len(payload) == 2+ticketLen {
+		// tls 1.2 message
+		ticketData.Ticket = payload[2:]
+		hsk.NewSessionTicket = ticketData
+		return nil
+	}
+
+	ticketData.TLS13 = true
+	if len(payload) < 5 {
+		return ErrHandshakeBadLength
+	}
+	ticketData.AgeAdd = uint32(payload[0])<<24 | uint32(payload[1])<<16 | uint32(payload[2])<<8 | uint32(payload[3])
+	nonceLen := int(payload[4])
+	payload = payload[5:]
+	if len(payload) < nonceLen+2 {
+		return ErrHandshakeBadLength
+	}
+	ticketData.Nonce = payload[:nonceLen]
+	payload = payload[nonceLen:]
+
+	ticketLen = int(payload[0])<<8 | int(payload[1])
+	payload = payload[2:]
+	if ticketLen == 0 || len(payload) < ticketLen+2 {
+		return ErrHandshakeBadLength
+	}
+	ticketData.Ticket = payload[:ticketLen]
+	payload = payload[ticketLen:]
+
+	extensionsLen := int(payload[0])<<8 | int(payload[1])
+	payload = payload[2:]
+	if len(payload) != extensionsLen {
+		return ErrHandshakeBadLength
+	}
+	var err error
+	ticketData.Extensions, err = appendExtensionsFromBytes(nil, payload, opts.MaxExtensions)
+	if err != nil && !opts.Lenient {
+		return err
+	}
+	if opts.DecodeExtensions {
+		ticketData.ExtInfo = &ExtensionsInfo{}
+		decodeExtensionsInfo(ticketData.ExtInfo, HandshakeTypeNewSessionTicket, ticketData.Extensions)
+	}
+
+	hsk.NewSessionTicket = ticketData
+	return err
+}
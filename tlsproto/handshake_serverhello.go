@@ -3,6 +3,7 @@
 package tlsproto
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/luisguillenc/tlslayer"
@@ -12,6 +13,17 @@ const (
 	serverHelloRandomLen = 32
 )
 
+// helloRetryRequestRandom is the special value of random in a hello retry request (rfc8446)
+var helloRetryRequestRandom = []byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11, 0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E, 0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+// downgradeSentinel is the prefix of the last bytes of random sent by a tls 1.3
+// server negotiating a lower version, last byte is 0x01 for tls 1.2 and 0x00
+// for tls 1.1 or below (rfc8446)
+var downgradeSentinel = []byte{0x44, 0x4F, 0x57, 0x4E, 0x47, 0x52, 0x44}
+
 // ServerHelloData stores data from ServerHello messages
 type ServerHelloData struct {
 	ServerVersion     tlslayer.ProtocolVersion `json:"serverVersion"`
@@ -23,6 +35,27 @@ type ServerHelloData struct {
 	ExtensionsLen uint16          `json:"extensionsLen"`
 	Extensions    []Extension     `json:"extensions,omitempty"`
 	ExtInfo       *ExtensionsInfo `json:"extInfo,omitempty"`
+
+	// extInfoBuf is kept between decodes when the struct is reused
+	extInfoBuf *ExtensionsInfo
+}
+
+// reset clears the struct keeping buffers, so it can be reused without allocations
+func (hs *ServerHelloData) reset() {
+	*hs = ServerHelloData{
+		Extensions: hs.Extensions[:0],
+		extInfoBuf: hs.extInfoBuf,
+	}
+}
+
+// newExtInfo returns an empty ExtensionsInfo reusing the buffer if it exists
+func (hs *ServerHelloData) newExtInfo() *ExtensionsInfo {
+	if hs.extInfoBuf == nil {
+		hs.extInfoBuf = &ExtensionsInfo{}
+	} else {
+		hs.extInfoBuf.reset()
+	}
+	return hs.extInfoBuf
 }
 
 func (hs *ServerHelloData) String() string {
@@ -36,12 +69,60 @@ func (hs *ServerHelloData) String() string {
 	return str
 }
 
+// IsHelloRetryRequest returns true if server hello is a TLS 1.3 hello retry request
+func (hs *ServerHelloData) IsHelloRetryRequest() bool {
+	return bytes.Equal(hs.Random, helloRetryRequestRandom)
+}
+
+// DowngradeSentinel returns the version signaled by the downgrade sentinel of
+// random: TLS 1.2 for "DOWNGRD\x01", TLS 1.1 for "DOWNGRD\x00" and zero if random
+// doesn't end with a sentinel
+func (hs *ServerHelloData) DowngradeSentinel() tlslayer.ProtocolVersion {
+	if len(hs.Random) < 8 {
+		return 0
+	}
+	tail := hs.Random[len(hs.Random)-8:]
+	if !bytes.Equal(tail[:7], downgradeSentinel) {
+		return 0
+	}
+	switch tail[7] {
+	case 0x01:
+		return tlslayer.VersionTLS12
+	case 0x00:
+		return tlslayer.VersionTLS11
+	}
+	return 0
+}
+
+// NegotiatedVersion returns the version selected by server. In TLS 1.3 server
+// version is always TLS 1.2 and the selected version is sent in supported_versions,
+// DTLS 1.3 is returned as is and TLSVersion maps it to TLS 1.3
+func (hs *ServerHelloData) NegotiatedVersion() tlslayer.ProtocolVersion {
+	versions := getSupportedVersions(HandshakeTypeServerHello, hs.Extensions, hs.ExtInfo)
+	if len(versions) == 1 && !versions[0].IsGREASE() {
+		return versions[0].Version()
+	}
+	return hs.ServerVersion
+}
+
+// SecureRenegotiation returns true if server accepts secure renegotiation
+func (hs *ServerHelloData) SecureRenegotiation() bool {
+	if hs.ExtInfo != nil {
+		return hs.ExtInfo.RenegotiationInfo
+	}
+	return false
+}
+
 //func newServerHelloDataFromBytes(payload []byte) (*ServerHelloData, error) {
-func decodeHskServerHello(hsk *Handshake, payload []byte) error {
+func decodeHskServerHello(hsk *Handshake, payload []byte, opts *DecodeOptions) error {
 	if len(payload) < 2 {
 		return ErrHandshakeBadLength
 	}
-	helloData := &ServerHelloData{}
+	helloData := hsk.newServerHello()
+	if opts.Lenient {
+		// partial data is returned on error
+		hsk.ServerHello = helloData
+	}
 	// Get server version
 	helloData.ServerVersion = tlslayer.ProtocolVersion(uint16(payload[0])<<8 | uint16(payload[1]))
 	payload = payload[2:]
@@ -100,15 +181,19 @@ func decodeHskServerHello(hsk *Handshake, payload []byte) error {
 		return ErrHandshakeExtBadLength
 	}
 	var err error
-	helloData.Extensions, err = getExtensionsFromBytes(payload)
-	if err != nil {
+	if helloData.Extensions == nil {
+		helloData.Extensions = make([]Extension, 0, extensionsCap)
+	}
+	helloData.Extensions, err = appendExtensionsFromBytes(helloData.Extensions, payload, opts.MaxExtensions)
+	if err != nil && !opts.Lenient {
 		return err
 	}
-	if DecodeExtensions {
-		helloData.ExtInfo, err = getExtensionsInfo(HandshakeTypeServerHello, helloData.Extensions)
-		if err != nil {
-			return err
-		}
+	if opts.DecodeExtensions {
+		helloData.ExtInfo = helloData.newExtInfo()
+		decodeExtensionsInfo(helloData.ExtInfo, HandshakeTypeServerHello, helloData.Extensions)
+	}
+	if err != nil {
+		return err
 	}
 	hsk.ServerHello = helloData
 	return nil
@@ -4,6 +4,9 @@ package tlsproto
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -219,6 +222,15 @@ func TestDecodeClientHello1Ext(t *testing.T) {
 	if !ch.ExtInfo.OSCP {
 		t.Errorf("expected OSCP")
 	}
+	if !ch.ExtInfo.ExtendedMasterSecret {
+		t.Errorf("expected extended_master_secret")
+	}
+	if !ch.SecureRenegotiation() {
+		t.Errorf("expected secure renegotiation")
+	}
+	if !ch.ExtInfo.EmptySessionTicket() {
+		t.Errorf("expected empty session_ticket, got: (len=%v)", ch.ExtInfo.SessionTicketLen)
+	}
 }
 
 func TestDecodeServerHello(t *testing.T) {
@@ -269,6 +281,12 @@ func TestDecodeServerHello(t *testing.T) {
 			t.Errorf("expected ecpointformat: %v, got: %v", ECPointFormat(1), sh.ExtInfo.ECPointFormats[1])
 		}
 	}
+	if !sh.SecureRenegotiation() {
+		t.Errorf("expected secure renegotiation")
+	}
+	if len(sh.ExtInfo.RenegotiatedConnection) != 0 {
+		t.Errorf("expected renegotiated_connection: (len=0), got: (len=%v)", len(sh.ExtInfo.RenegotiatedConnection))
+	}
 }
 
 func TestDecodeCertificate(t *testing.T) {
@@ -374,6 +392,12 @@ func TestGREASE(t *testing.T) {
 	if ch.Extensions[13].Type.IsGREASE() {
 		t.Errorf("expected extension grease: %v, got: %v", ExtPadding, ch.Extensions[13].Type)
 	}
+	if ch.ExtInfo.PaddingLen != ch.Extensions[13].Len {
+		t.Errorf("expected padding len: %v, got: %v", ch.Extensions[13].Len, ch.ExtInfo.PaddingLen)
+	}
+	if ch.ExtInfo.PaddingNonZero {
+		t.Errorf("unexpected non zero padding")
+	}
 }
 
 func TestExtKeyShare1(t *testing.T) {
@@ -434,3 +458,361 @@ func TestExtPSKKeyExchangeModes(t *testing.T) {
 		}
 	}
 }
+
+// buildClientHello returns a client hello handshake with the extensions passed
+func buildClientHello(extensions []byte) []byte {
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...)
+	body = append(body, 0x00, 0x00, 0x02, 0xc0, 0x2f, 0x01, 0x00)
+	body = append(body, byte(len(extensions)>>8), byte(len(extensions)))
+	body = append(body, extensions...)
+	hsk := []byte{byte(HandshakeTypeClientHello), byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	return append(hsk, body...)
+}
+
+func TestExtensionStatus(t *testing.T) {
+	payload := buildClientHello([]byte{
+		0x00, 0x00, 0x00, 0x01, 0xff, // malformed server_name
+		0x00, 0x0f, 0x00, 0x01, 0x01, // heartbeat
+		0xfe, 0x00, 0x00, 0x02, 0xaa, 0xbb, // unknown
+		0x00, 0x12, 0x00, 0x00, // signed_certificate_timestamp
+	})
+	handshake, err := NewHandshakeFromBytes(payload)
+	if err != nil {
+		t.Fatal("getting handshake from bytes:", err)
+	}
+	ch := handshake.ClientHello
+	if ch == nil || ch.ExtInfo == nil {
+		t.Fatal("ClientHello doesn't decoded")
+	}
+	expected := []ExtensionStatus{ExtStatusMalformed, ExtStatusOK, ExtStatusUnknown, ExtStatusOpaque}
+	if len(ch.Extensions) != len(expected) {
+		t.Fatalf("expected extensions: %v, got: %v", len(expected), len(ch.Extensions))
+	}
+	for i, status := range expected {
+		if ch.Extensions[i].Status != status {
+			t.Errorf("expected extension %v status: %v, got: %v", ch.Extensions[i].Type, status, ch.Extensions[i].Status)
+		}
+	}
+	if ch.Extensions[0].Err != ErrHandshakeExtBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtBadLength, ch.Extensions[0].Err)
+	}
+	data, err := json.Marshal(ch.Extensions[0])
+	if err != nil {
+		t.Fatal("encoding extension:", err)
+	}
+	if !bytes.Contains(data, []byte(`"error":"`+ErrHandshakeExtBadLength.Error()+`"`)) {
+		t.Errorf("expected error in json, got: %s", data)
+	}
+	if ch.Extensions[1].Error != "" {
+		t.Errorf("unexpected error: %v", ch.Extensions[1].Error)
+	}
+	if !bytes.Equal(ch.Extensions[2].Payload, []byte{0xaa, 0xbb}) {
+		t.Errorf("unexpected payload: %x", ch.Extensions[2].Payload)
+	}
+	if ch.ExtInfo.HeartbeatMode != HeartbeatPeerAllowedToSend {
+		t.Errorf("expected heartbeat decoded after malformed extension")
+	}
+	if len(MalformedExtensions(ch.Extensions)) != 1 {
+		t.Errorf("expected malformed extensions: 1, got: %v", len(MalformedExtensions(ch.Extensions)))
+	}
+}
+
+func TestDecodeOptions(t *testing.T) {
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(testRecordClientHello1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	opts := DefaultDecodeOptions()
+	opts.DecodeExtensions = false
+	opts.CopyBuffers = true
+	handshakes, err := NewHandshakesFromRecordWithOptions(tlsrecord, opts)
+	if err != nil {
+		t.Fatal("getting handshakes from record:", err)
+	}
+	ch := handshakes[0].ClientHello
+	if ch == nil {
+		t.Fatal("ClientHello doesn't decoded")
+	}
+	if ch.ExtInfo != nil {
+		t.Error("unexpected extensions decoded")
+	}
+	if &ch.Random[0] == &tlsrecord.Payload()[6] {
+		t.Error("random aliases record payload")
+	}
+
+	opts = DefaultDecodeOptions()
+	opts.MaxExtensions = 4
+	_, err = NewHandshakesFromRecordWithOptions(tlsrecord, opts)
+	if err != ErrHandshakeExtTooMany {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtTooMany, err)
+	}
+	opts.Lenient = true
+	handshakes, err = NewHandshakesFromRecordWithOptions(tlsrecord, opts)
+	if err != ErrHandshakeExtTooMany {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeExtTooMany, err)
+	}
+	if len(handshakes) != 1 || handshakes[0].ClientHello == nil || len(handshakes[0].ClientHello.Extensions) != 4 {
+		t.Error("expected partial client hello in lenient mode")
+	}
+
+	opts = DefaultDecodeOptions()
+	opts.MaxHandshakeSize = 256
+	_, err = NewHandshakeFromBytesWithOptions(tlsrecord.Payload(), opts)
+	if err != ErrHandshakeTooLarge {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeTooLarge, err)
+	}
+}
+
+func TestDecodeOptionsCertificates(t *testing.T) {
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(testRecordMultipleHsk1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	opts := DefaultDecodeOptions()
+	opts.ParseCertificates = false
+	handshakes, err := NewHandshakesFromRecordWithOptions(tlsrecord, opts)
+	if err != nil {
+		t.Fatal("getting handshakes from record:", err)
+	}
+	certh := handshakes[1].Certificate
+	if certh == nil {
+		t.Fatal("CertificateData doesn't loaded")
+	}
+	if certh.CertificatesLen == 0 || len(certh.Certificates) != 0 {
+		t.Errorf("unexpected certificates parsed: %v", len(certh.Certificates))
+	}
+
+	// truncate record in the middle of certificate message
+	tlsrecord.BaseLayer.Payload = tlsrecord.Payload()[:200]
+	opts = DefaultDecodeOptions()
+	opts.Lenient = true
+	handshakes, err = NewHandshakesFromRecordWithOptions(tlsrecord, opts)
+	if err != ErrHandshakeFragmented {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeFragmented, err)
+	}
+	if len(handshakes) != 1 || handshakes[0].ServerHello == nil {
+		t.Errorf("expected server hello decoded in lenient mode")
+	}
+}
+
+func TestLazyCertificates(t *testing.T) {
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(testRecordCertificate1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	opts := DefaultDecodeOptions()
+	opts.LazyCertificates = true
+	opts.CertificateCache = NewCertificateCache(8)
+	handshakes, err := NewHandshakesFromRecordWithOptions(tlsrecord, opts)
+	if err != nil {
+		t.Fatal("getting handshakes from record:", err)
+	}
+	certh := handshakes[0].Certificate
+	if certh == nil {
+		t.Fatal("CertificateData doesn't loaded")
+	}
+	if len(certh.Certificates) != 0 {
+		t.Errorf("unexpected certificates parsed: %v", len(certh.Certificates))
+	}
+	if len(certh.Entries) != 2 {
+		t.Fatalf("expected entries: 2, got: %v", len(certh.Entries))
+	}
+	sum := sha256.Sum256(certh.Entries[0].Raw)
+	if !bytes.Equal(certh.Entries[0].SHA256, sum[:]) || len(certh.Entries[0].SHA1) != sha1.Size {
+		t.Errorf("unexpected fingerprints: %v", certh.Entries[0])
+	}
+
+	certs, err := certh.Parse()
+	if err != nil {
+		t.Fatal("parsing certificates:", err)
+	}
+	if len(certs) != 2 || certs[0].Subject.CommonName != "*.services.mozilla.com" {
+		t.Errorf("unexpected certificates: %v", certs)
+	}
+	// same chain must be returned from cache
+	other, err := certh.Entries[0].Certificate()
+	if err != nil || other != certs[0] {
+		t.Errorf("expected cached certificate")
+	}
+	opts.CertificateCache.Flush()
+	other, err = certh.Entries[0].Certificate()
+	if err != nil || other == certs[0] {
+		t.Errorf("unexpected cached certificate after flush")
+	}
+
+	// chain without certificates is parsed once
+	empty := &CertificateData{}
+	if certs, err := empty.Parse(); err != nil || len(certs) != 0 || !empty.parsed {
+		t.Errorf("unexpected parse of empty chain: %v, error: %v", certs, err)
+	}
+}
+
+func TestNegotiatedVersion(t *testing.T) {
+	tests := []struct {
+		sh   *ServerHelloData
+		want tlslayer.ProtocolVersion
+	}{
+		{&ServerHelloData{ServerVersion: tlslayer.VersionTLS12}, tlslayer.VersionTLS12},
+		{&ServerHelloData{ServerVersion: tlslayer.VersionTLS10, ExtInfo: &ExtensionsInfo{}}, tlslayer.VersionTLS10},
+		{&ServerHelloData{
+			ServerVersion: tlslayer.VersionTLS12,
+			ExtInfo:       &ExtensionsInfo{SupportedVersions: []SupportedVersion{0x0304}},
+		}, tlslayer.VersionTLS13},
+		{&ServerHelloData{
+			ServerVersion: tlslayer.VersionTLS12,
+			ExtInfo:       &ExtensionsInfo{SupportedVersions: []SupportedVersion{0x7f1c}},
+		}, tlslayer.VersionTLS13},
+		// extensions not decoded
+		{&ServerHelloData{
+			ServerVersion: tlslayer.VersionTLS12,
+			Extensions:    []Extension{{Type: ExtSupportedVersions, Len: 2, Payload: []byte{0x03, 0x04}}},
+		}, tlslayer.VersionTLS13},
+		// dtls 1.3
+		{&ServerHelloData{
+			ServerVersion: tlslayer.VersionDTLS12,
+			ExtInfo:       &ExtensionsInfo{SupportedVersions: []SupportedVersion{0xfefc}},
+		}, tlslayer.VersionDTLS13},
+	}
+	for i, test := range tests {
+		if got := test.sh.NegotiatedVersion(); got != test.want {
+			t.Errorf("test %d: expected version: %v, got: %v", i, test.want, got)
+		}
+	}
+
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(testRecordServerHello1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	handshake, err := NewHandshakeFromBytes(tlsrecord.Payload())
+	if err != nil {
+		t.Fatal("getting handshake from bytes:", err)
+	}
+	if v := handshake.ServerHello.NegotiatedVersion(); v != handshake.ServerHello.ServerVersion {
+		t.Errorf("expected version: %v, got: %v", handshake.ServerHello.ServerVersion, v)
+	}
+}
+
+func TestMaxOfferedVersion(t *testing.T) {
+	tests := []struct {
+		ch   *ClientHelloData
+		want tlslayer.ProtocolVersion
+	}{
+		{&ClientHelloData{ClientVersion: tlslayer.VersionTLS11}, tlslayer.VersionTLS11},
+		{&ClientHelloData{
+			ClientVersion: tlslayer.VersionTLS12,
+			ExtInfo:       &ExtensionsInfo{SupportedVersions: []SupportedVersion{0x7a7a, 0x0304, 0x0303}},
+		}, tlslayer.VersionTLS13},
+		{&ClientHelloData{
+			ClientVersion: tlslayer.VersionTLS12,
+			ExtInfo:       &ExtensionsInfo{SupportedVersions: []SupportedVersion{0x7a7a, 0x0303}},
+		}, tlslayer.VersionTLS12},
+		// extensions not decoded
+		{&ClientHelloData{
+			ClientVersion: tlslayer.VersionTLS12,
+			Extensions:    []Extension{{Type: ExtSupportedVersions, Len: 5, Payload: []byte{0x04, 0x7f, 0x17, 0x03, 0x03}}},
+		}, tlslayer.VersionTLS13},
+		// dtls versions decrease with newer versions
+		{&ClientHelloData{
+			ClientVersion: tlslayer.VersionDTLS12,
+			ExtInfo:       &ExtensionsInfo{SupportedVersions: []SupportedVersion{0x7a7a, 0xfefc, 0xfefd}},
+		}, tlslayer.VersionDTLS13},
+		{&ClientHelloData{
+			ClientVersion: tlslayer.VersionDTLS12,
+			ExtInfo:       &ExtensionsInfo{SupportedVersions: []SupportedVersion{0xfefd, 0xfeff}},
+		}, tlslayer.VersionDTLS12},
+	}
+	for i, test := range tests {
+		if got := test.ch.MaxOfferedVersion(); got != test.want {
+			t.Errorf("test %d: expected version: %v, got: %v", i, test.want, got)
+		}
+	}
+
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(testRecordClientHello1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	handshake, err := NewHandshakeFromBytes(tlsrecord.Payload())
+	if err != nil {
+		t.Fatal("getting handshake from bytes:", err)
+	}
+	if v := handshake.ClientHello.MaxOfferedVersion(); v != tlslayer.VersionTLS13 {
+		t.Errorf("expected version: %v, got: %v", tlslayer.VersionTLS13, v)
+	}
+}
+
+func TestDowngradeSignals(t *testing.T) {
+	sentinel := []byte{0x44, 0x4F, 0x57, 0x4E, 0x47, 0x52, 0x44}
+	tests := []struct {
+		last byte
+		want tlslayer.ProtocolVersion
+	}{
+		{0x01, tlslayer.VersionTLS12},
+		{0x00, tlslayer.VersionTLS11},
+		{0x02, 0},
+	}
+	for _, test := range tests {
+		random := append(make([]byte, 24), sentinel...)
+		sh := &ServerHelloData{Random: append(random, test.last)}
+		if got := sh.DowngradeSentinel(); got != test.want {
+			t.Errorf("expected sentinel %x: %v, got: %v", test.last, test.want, got)
+		}
+	}
+	if got := (&ServerHelloData{Random: make([]byte, 32)}).DowngradeSentinel(); got != 0 {
+		t.Errorf("unexpected sentinel: %v", got)
+	}
+
+	ch := &ClientHelloData{CipherSuites: []CipherSuite{0xc02f, CipherSuiteFallbackSCSV}}
+	if !ch.FallbackSCSV() {
+		t.Error("expected fallback scsv")
+	}
+	ch.CipherSuites = ch.CipherSuites[:1]
+	if ch.FallbackSCSV() {
+		t.Error("unexpected fallback scsv")
+	}
+}
+
+func TestDecodeNewSessionTicket(t *testing.T) {
+	// tls 1.2
+	payload := []byte{0x04, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x1c, 0x20, 0x00, 0x04, 0x01, 0x02, 0x03, 0x04}
+	handshake, err := NewHandshakeFromBytes(payload)
+	if err != nil {
+		t.Fatal("getting handshake from bytes:", err)
+	}
+	nst := handshake.NewSessionTicket
+	if nst == nil {
+		t.Fatal("NewSessionTicket doesn't decoded")
+	}
+	if nst.TLS13 || nst.Lifetime != 7200 || !bytes.Equal(nst.Ticket, []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("unexpected tls 1.2 ticket: %v", nst)
+	}
+
+	// tls 1.3 with early_data
+	body := []byte{
+		0x00, 0x00, 0x1c, 0x20, // lifetime
+		0xaa, 0xbb, 0xcc, 0xdd, // age add
+		0x01, 0x00, // nonce
+		0x00, 0x02, 0x05, 0x06, // ticket
+		0x00, 0x08, 0x00, 0x2a, 0x00, 0x04, 0x00, 0x00, 0x40, 0x00,
+	}
+	payload = append([]byte{0x04, 0x00, 0x00, byte(len(body))}, body...)
+	handshake, err = NewHandshakeFromBytes(payload)
+	if err != nil {
+		t.Fatal("getting handshake from bytes:", err)
+	}
+	nst = handshake.NewSessionTicket
+	if nst == nil {
+		t.Fatal("NewSessionTicket doesn't decoded")
+	}
+	if !nst.TLS13 || nst.AgeAdd != 0xaabbccdd || len(nst.Nonce) != 1 || !bytes.Equal(nst.Ticket, []byte{0x05, 0x06}) {
+		t.Errorf("unexpected tls 1.3 ticket: %v", nst)
+	}
+	if nst.ExtInfo == nil || !nst.ExtInfo.EarlyData || nst.ExtInfo.MaxEarlyDataSize != 16384 {
+		t.Errorf("expected early data: 16384, got: %v", nst.ExtInfo)
+	}
+
+	_, err = NewHandshakeFromBytes([]byte{0x04, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00})
+	if err != ErrHandshakeBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHandshakeBadLength, err)
+	}
+}
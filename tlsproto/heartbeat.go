@@ -0,0 +1,176 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/luisguillenc/tlslayer"
+)
+
+// HeartbeatMessageType is the type of a heartbeat message defined in rfc6520
+type HeartbeatMessageType uint8
+
+// HeartbeatMessageType possible values
+const (
+	HeartbeatRequest  HeartbeatMessageType = 1
+	HeartbeatResponse HeartbeatMessageType = 2
+)
+
+// heartbeatMinPadding is the minimum length of the padding of a message
+const heartbeatMinPadding = 16
+
+func (h HeartbeatMessageType) getDesc() string {
+	switch h {
+	case HeartbeatRequest:
+		return "heartbeat_request"
+	case HeartbeatResponse:
+		return "heartbeat_response"
+	default:
+		return "unknown"
+	}
+}
+
+func (h HeartbeatMessageType) String() string {
+	return fmt.Sprintf("%s(%d)", h.getDesc(), h)
+}
+
+// IsValid method checks if it's a valid value
+func (h HeartbeatMessageType) IsValid() bool {
+	return h == HeartbeatRequest || h == HeartbeatResponse
+}
+
+// Heartbeat is the struct for tls messages of heartbeat protocol. Only
+// messages sent before change_cipher_spec can be decoded.
+type Heartbeat struct {
+	TLSMessage
+
+	Type HeartbeatMessageType `json:"type"`
+	// PayloadLength is the length declared in the message, it may be greater
+	// than Payload if message is malformed
+	PayloadLength uint16 `json:"payloadLength"`
+	Payload       []byte `json:"payload,omitempty"`
+	Padding       []byte `json:"padding,omitempty"`
+
+	contents []byte
+}
+
+func (hb *Heartbeat) String() string {
+	return fmt.Sprintf("%s (payload_length=%d len=%d padding=%d)", hb.Type, hb.PayloadLength, len(hb.Payload), len(hb.Padding))
+}
+
+// GetType returns the content type
+func (hb *Heartbeat) GetType() tlslayer.ContentType {
+	return tlslayer.ContentTypeHeartbeat
+}
+
+// IsHeartbleed returns true if message is a request that declares a payload
+// greater than the message, the signature of Heartbleed (CVE-2014-0160)
+func (hb *Heartbeat) IsHeartbleed() bool {
+	return hb.Type == HeartbeatRequest && int(hb.PayloadLength) > len(hb.contents)-3
+}
+
+// NewHeartbeatFromBytes creates a heartbeat from a byte slice with the payload
+func NewHeartbeatFromBytes(payload []byte) (*Heartbeat, error) {
+	hb := &Heartbeat{}
+	if err := hb.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	}
+
+	return hb, nil
+}
+
+// DecodeFromBytes decodes a heartbeat from a byte slice with the payload reusing the struct.
+// If payload length is greater than the message ErrHeartbeatBadLength is returned
+// and the struct stores the data of the message, so it can be inspected.
+func (hb *Heartbeat) DecodeFromBytes(payload []byte, df gopacket.DecodeFeedback) error {
+	if len(payload) < 3 {
+		return ErrWrowngLenPayload
+	}
+
+	htype := HeartbeatMessageType(payload[0])
+	if !htype.IsValid() {
+		return ErrHeartbeatInvalidType
+	}
+	hb.Type = htype
+	hb.PayloadLength = uint16(payload[1])<<8 | uint16(payload[2])
+	hb.contents = payload
+
+	data := payload[3:]
+	if int(hb.PayloadLength) > len(data) {
+		hb.Payload = data
+		hb.Padding = nil
+		return ErrHeartbeatBadLength
+	}
+	hb.Payload = data[:hb.PayloadLength]
+	hb.Padding = data[hb.PayloadLength:]
+
+	return nil
+}
+
+// ValidPadding returns true if padding has the minimum length required by rfc
+func (hb *Heartbeat) ValidPadding() bool {
+	return len(hb.Padding) >= heartbeatMinPadding
+}
+
+// CanDecode satisfaces the interface
+func (hb *Heartbeat) CanDecode() gopacket.LayerClass {
+	return LayerTypeHeartbeat
+}
+
+// LayerType satisfaces the interface
+func (hb *Heartbeat) LayerType() gopacket.LayerType {
+	return LayerTypeHeartbeat
+}
+
+// NextLayerType satisfaces the interface, heartbeat is the last layer
+func (hb *Heartbeat) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+// LayerContents satisfaces the interface
+func (hb *Heartbeat) LayerContents() []byte {
+	return hb.contents
+}
+
+// LayerPayload satisfaces the interface, heartbeat payload isn't a layer
+func (hb *Heartbeat) LayerPayload() []byte {
+	return nil
+}
+
+// decodeHeartbeatLayer decodes the byte slice and add heartbeat layer to packet
+// builder, malformed messages are added so they can be inspected
+func decodeHeartbeatLayer(data []byte, p gopacket.PacketBuilder) error {
+	hb := &Heartbeat{}
+	err := hb.DecodeFromBytes(data, p)
+	if err != nil && err != ErrHeartbeatBadLength {
+		return err
+	}
+	p.AddLayer(hb)
+
+	return err
+}
+
+// NewHeartbeatFromRecord creates a heartbeat from a TLS Record
+func NewHeartbeatFromRecord(tlsr *tlslayer.TLSRecord) (*Heartbeat, error) {
+	if tlsr.Type != tlslayer.ContentTypeHeartbeat {
+		return nil, ErrUnexpectedRecordType
+	}
+	return NewHeartbeatFromBytes(tlsr.Payload())
+}
+
+// CheckHeartbleed returns true if the record is a plaintext heartbeat request
+// that declares a payload greater than the record length. Protected heartbeats
+// can't be inspected and records whose payload is truncated or cleared are skipped.
+func CheckHeartbleed(tlsr *tlslayer.TLSRecord) bool {
+	if tlsr.Type != tlslayer.ContentTypeHeartbeat || len(tlsr.Payload()) != int(tlsr.Len) {
+		return false
+	}
+	hb := Heartbeat{}
+	err := hb.DecodeFromBytes(tlsr.Payload(), gopacket.NilDecodeFeedback)
+	if err != nil && err != ErrHeartbeatBadLength {
+		return false
+	}
+	return hb.Type == HeartbeatRequest && int(hb.PayloadLength) > int(tlsr.Len)-3
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlsproto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/luisguillenc/tlslayer"
+)
+
+// request with payload "hb" and 16 bytes of padding
+var testRecordHeartbeat1 = []byte{
+	0x18, 0x03, 0x02, 0x00, 0x15, 0x01, 0x00, 0x02, 0x68, 0x62,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// heartbleed request declaring 16384 bytes of payload
+var testRecordHeartbleed1 = []byte{
+	0x18, 0x03, 0x02, 0x00, 0x03, 0x01, 0x40, 0x00,
+}
+
+func TestDecodeHeartbeat(t *testing.T) {
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(testRecordHeartbeat1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	hb, err := NewHeartbeatFromRecord(tlsrecord)
+	if err != nil {
+		t.Fatal("getting heartbeat from record:", err)
+	}
+	if hb.Type != HeartbeatRequest || hb.PayloadLength != 2 || !bytes.Equal(hb.Payload, []byte("hb")) {
+		t.Errorf("unexpected heartbeat: %v", hb)
+	}
+	if len(hb.Padding) != 16 || !hb.ValidPadding() {
+		t.Errorf("expected padding: 16, got: %v", len(hb.Padding))
+	}
+	if hb.IsHeartbleed() || CheckHeartbleed(tlsrecord) {
+		t.Error("unexpected heartbleed")
+	}
+
+	if _, err := NewHeartbeatFromBytes([]byte{0x03, 0x00, 0x00}); err != ErrHeartbeatInvalidType {
+		t.Errorf("expected error: %v, got: %v", ErrHeartbeatInvalidType, err)
+	}
+}
+
+func TestHeartbleed(t *testing.T) {
+	tlsrecord := &tlslayer.TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(testRecordHeartbleed1, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("bad tlsrecord")
+	}
+	if _, err := NewHeartbeatFromRecord(tlsrecord); err != ErrHeartbeatBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHeartbeatBadLength, err)
+	}
+	hb := &Heartbeat{}
+	if err := hb.DecodeFromBytes(tlsrecord.Payload(), gopacket.NilDecodeFeedback); err != ErrHeartbeatBadLength {
+		t.Errorf("expected error: %v, got: %v", ErrHeartbeatBadLength, err)
+	}
+	if hb.PayloadLength != 16384 || !hb.IsHeartbleed() {
+		t.Errorf("expected heartbleed: %v", hb)
+	}
+	if !CheckHeartbleed(tlsrecord) {
+		t.Error("expected heartbleed detected")
+	}
+	// truncated record declaring the payload isn't heartbleed
+	truncated := &tlslayer.TLSRecord{}
+	truncated.DecodeFromBytes([]byte{0x18, 0x03, 0x02, 0x00, 0x20, 0x01, 0x00, 0x10}, gopacket.NilDecodeFeedback)
+	if CheckHeartbleed(truncated) {
+		t.Error("unexpected heartbleed in truncated record")
+	}
+	// responses aren't requests
+	tlsrecord.Payload()[0] = byte(HeartbeatResponse)
+	defer func() { tlsrecord.Payload()[0] = byte(HeartbeatRequest) }()
+	if CheckHeartbleed(tlsrecord) {
+		t.Error("unexpected heartbleed in response")
+	}
+}
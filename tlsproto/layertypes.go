@@ -0,0 +1,81 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlsproto
+
+import (
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/luisguillenc/tlslayer"
+)
+
+// Registered layers in gopacket for tls messages
+var (
+	LayerTypeHandshake = gopacket.RegisterLayerType(
+		1444,
+		gopacket.LayerTypeMetadata{
+			Name:    "TLSHandshake",
+			Decoder: gopacket.DecodeFunc(decodeHandshakeLayer),
+		},
+	)
+	LayerTypeAlert = gopacket.RegisterLayerType(
+		1445,
+		gopacket.LayerTypeMetadata{
+			Name:    "TLSAlert",
+			Decoder: gopacket.DecodeFunc(decodeAlertLayer),
+		},
+	)
+	LayerTypeChangeCipherSpec = gopacket.RegisterLayerType(
+		1446,
+		gopacket.LayerTypeMetadata{
+			Name:    "TLSChangeCipherSpec",
+			Decoder: gopacket.DecodeFunc(decodeChangeCipherSpecLayer),
+		},
+	)
+	LayerTypeApplicationData = gopacket.RegisterLayerType(
+		1447,
+		gopacket.LayerTypeMetadata{
+			Name:    "TLSApplicationData",
+			Decoder: gopacket.DecodeFunc(decodeApplicationDataLayer),
+		},
+	)
+	LayerTypeHeartbeat = gopacket.RegisterLayerType(
+		1448,
+		gopacket.LayerTypeMetadata{
+			Name:    "TLSHeartbeat",
+			Decoder: gopacket.DecodeFunc(decodeHeartbeatLayer),
+		},
+	)
+	LayerTypeDTLSHandshake = gopacket.RegisterLayerType(
+		1450,
+		gopacket.LayerTypeMetadata{
+			Name:    "DTLSHandshake",
+			Decoder: gopacket.DecodeFunc(decodeDTLSHandshakeLayer),
+		},
+	)
+)
+
+var registerOnce sync.Once
+
+// RegisterLayers sets the layers of this package as the next layers of tls and
+// dtls records, so gopacket.NewPacket and DecodingLayerParser decode the
+// messages of the records. Until it's called the next layer of records is
+// payload. It must be called before decoding packets, e.g. from an init function.
+func RegisterLayers() {
+	registerOnce.Do(registerLayers)
+}
+
+func registerLayers() {
+	tlslayer.RegisterContentTypeLayerType(tlslayer.ContentTypeHandshake, LayerTypeHandshake)
+	tlslayer.RegisterContentTypeLayerType(tlslayer.ContentTypeAlert, LayerTypeAlert)
+	tlslayer.RegisterContentTypeLayerType(tlslayer.ContentTypeChangeCipherSpec, LayerTypeChangeCipherSpec)
+	tlslayer.RegisterContentTypeLayerType(tlslayer.ContentTypeApplicationData, LayerTypeApplicationData)
+	tlslayer.RegisterContentTypeLayerType(tlslayer.ContentTypeHeartbeat, LayerTypeHeartbeat)
+
+	// dtls messages have the same format except handshake
+	tlslayer.RegisterDTLSContentTypeLayerType(tlslayer.ContentTypeHandshake, LayerTypeDTLSHandshake)
+	tlslayer.RegisterDTLSContentTypeLayerType(tlslayer.ContentTypeAlert, LayerTypeAlert)
+	tlslayer.RegisterDTLSContentTypeLayerType(tlslayer.ContentTypeChangeCipherSpec, LayerTypeChangeCipherSpec)
+	tlslayer.RegisterDTLSContentTypeLayerType(tlslayer.ContentTypeApplicationData, LayerTypeApplicationData)
+	tlslayer.RegisterDTLSContentTypeLayerType(tlslayer.ContentTypeHeartbeat, LayerTypeHeartbeat)
+}
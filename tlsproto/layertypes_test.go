@@ -0,0 +1,106 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlsproto
+
+import (
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/luisguillenc/tlslayer"
+)
+
+func TestDecodingLayerParser(t *testing.T) {
+	RegisterLayers()
+	var packetClient, packetServer []byte
+	if err := loadBinFile(&packetClient, "fullpacket-client1.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadBinFile(&packetServer, "fullpacket-server1.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	var eth layers.Ethernet
+	var ip4 layers.IPv4
+	var tcp layers.TCP
+	var tls tlslayer.TLSRecord
+	var alert Alert
+	var ccs ChangeCipherSpec
+	var appdata ApplicationData
+	hsk := NewHandshakeDecoder(DefaultDecodeOptions())
+	parser := gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet,
+		&eth, &ip4, &tcp, &tls, hsk, &alert, &ccs, &appdata)
+	decoded := []gopacket.LayerType{}
+
+	if err := parser.DecodeLayers(packetClient, &decoded); err != nil {
+		t.Fatal("decoding client packet:", err)
+	}
+	want := []gopacket.LayerType{layers.LayerTypeEthernet, layers.LayerTypeIPv4,
+		layers.LayerTypeTCP, tlslayer.LayerTypeTLSRecord, LayerTypeHandshake}
+	if len(decoded) != len(want) {
+		t.Fatalf("expected layers: %v, got: %v", want, decoded)
+	}
+	for i := range want {
+		if decoded[i] != want[i] {
+			t.Errorf("expected layer: %v, got: %v", want[i], decoded[i])
+		}
+	}
+	if len(hsk.Handshakes) != 1 || hsk.Handshakes[0].ClientHello == nil {
+		t.Fatal("expected clienthello decoded")
+	}
+
+	if err := parser.DecodeLayers(packetServer, &decoded); err != nil {
+		t.Fatal("decoding server packet:", err)
+	}
+	if len(hsk.Handshakes) != 1 || hsk.Handshakes[0].ServerHello == nil {
+		t.Fatal("expected serverhello decoded")
+	}
+}
+
+func TestNewPacket(t *testing.T) {
+	RegisterLayers()
+	var packetClient []byte
+	if err := loadBinFile(&packetClient, "fullpacket-client1.bin"); err != nil {
+		t.Fatal(err)
+	}
+	packet := gopacket.NewPacket(packetClient, layers.LayerTypeEthernet, gopacket.DecodeOptions{DecodeStreamsAsDatagrams: true})
+	if err := packet.ErrorLayer(); err != nil {
+		t.Fatal("decoding packet:", err.Error())
+	}
+	if _, ok := packet.ApplicationLayer().(*tlslayer.TLSRecord); !ok {
+		t.Errorf("expected application layer: %v, got: %v", tlslayer.LayerTypeTLSRecord, packet.ApplicationLayer())
+	}
+	layer := packet.Layer(LayerTypeHandshake)
+	if layer == nil {
+		t.Fatalf("expected layer: %v, got: %v", LayerTypeHandshake, packet.Layers())
+	}
+	hsk := layer.(*HandshakeDecoder)
+	if len(hsk.Handshakes) != 1 || hsk.Handshakes[0].ClientHello == nil {
+		t.Error("expected clienthello decoded")
+	}
+}
+
+func TestMessageLayers(t *testing.T) {
+	RegisterLayers()
+	tests := []struct {
+		record []byte
+		layer  gopacket.DecodingLayer
+		want   gopacket.LayerType
+	}{
+		{testRecordAlert1, &Alert{}, LayerTypeAlert},
+		{testRecordCCS1, &ChangeCipherSpec{}, LayerTypeChangeCipherSpec},
+		{testRecordClientHello1, NewHandshakeDecoder(DefaultDecodeOptions()), LayerTypeHandshake},
+		{testRecordHeartbeat1, &Heartbeat{}, LayerTypeHeartbeat},
+	}
+	for _, test := range tests {
+		tlsrecord := decodeTestRecord(test.record, t)
+		if tlsrecord.NextLayerType() != test.want {
+			t.Errorf("expected next layer: %v, got: %v", test.want, tlsrecord.NextLayerType())
+		}
+		if test.layer.CanDecode() != test.want {
+			t.Errorf("expected layer class: %v, got: %v", test.want, test.layer.CanDecode())
+		}
+		if err := test.layer.DecodeFromBytes(tlsrecord.LayerPayload(), gopacket.NilDecodeFeedback); err != nil {
+			t.Errorf("decoding %v: %v", test.want, err)
+		}
+	}
+}
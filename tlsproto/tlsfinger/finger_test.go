@@ -128,3 +128,28 @@ func TestFingerClientHelloGREASE(t *testing.T) {
 		t.Errorf("expected digest: 46efd49abcca8ea9baa932da68fdb529, got %v", digest)
 	}
 }
+
+func TestFingerDTLSClientHello(t *testing.T) {
+	body := []byte{
+		0xfe, 0xfd,
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+		0x00, 0x04, 0xde, 0xad, 0xbe, 0xef, 0x00, 0x04, 0xc0, 0x2b, 0xc0, 0x2f, 0x01, 0x00,
+		0x00, 0x0e, 0x00, 0x0a, 0x00, 0x04, 0x00, 0x02, 0x00, 0x17, 0x00, 0x0b, 0x00, 0x02, 0x01, 0x00,
+	}
+	data := append([]byte{0x01, 0x00, 0x00, byte(len(body)), 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, byte(len(body))}, body...)
+	r := tlsproto.NewDTLSReassembler(tlsproto.DefaultDecodeOptions())
+	handshakes, err := r.AddFragments(data)
+	if err != nil {
+		t.Fatal("getting dtls handshake:", err)
+	}
+	if len(handshakes) != 1 || handshakes[0].ClientHello == nil {
+		t.Fatalf("expected clienthello, got: %v", handshakes)
+	}
+
+	expected := "65277,49195-49199,10-11,23,0"
+	finger, _ := GetJA3(handshakes[0].ClientHello)
+	if finger != expected {
+		t.Errorf("expected fingerprint: %v, got %v", expected, finger)
+	}
+}
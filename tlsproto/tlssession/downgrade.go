@@ -0,0 +1,53 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlssession
+
+import (
+	"fmt"
+
+	"github.com/luisguillenc/tlslayer"
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+// DowngradeReport stores the evidences of a protocol downgrade found in a session
+type DowngradeReport struct {
+	// FallbackSCSV is true if client sent TLS_FALLBACK_SCSV, so it's retrying
+	// the connection with a lower version than it supports
+	FallbackSCSV bool `json:"fallbackSCSV"`
+	// InappropriateFallback is true if server rejected the fallback with an alert
+	InappropriateFallback bool `json:"inappropriateFallback"`
+	// Sentinel is the version signaled by the downgrade sentinel of server random
+	Sentinel tlslayer.ProtocolVersion `json:"sentinel,omitempty"`
+	// Detected is true if the evidences show that the connection was downgraded
+	Detected bool   `json:"detected"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (r DowngradeReport) String() string {
+	if r.Detected {
+		return fmt.Sprintf("downgrade detected: %s", r.Reason)
+	}
+	return "no downgrade"
+}
+
+// updateDowngrade checks the evidences of the session for a downgrade
+func (s *Session) updateDowngrade() {
+	r := &s.Downgrade
+	switch {
+	case r.InappropriateFallback:
+		r.Detected = true
+		r.Reason = "server rejected client fallback"
+	case r.Sentinel != 0 && s.ClientHello != nil && r.Sentinel.Less(s.OfferedVersion):
+		// a client that offered a higher version must abort the connection
+		r.Detected = true
+		r.Reason = fmt.Sprintf("server sentinel %v with client offering %v", r.Sentinel, s.OfferedVersion)
+	}
+}
+
+// addDowngradeAlert checks if the alert is the response to a fallback
+func (s *Session) addDowngradeAlert(dir Direction, alert *tlsproto.Alert) {
+	if dir == ServerToClient && alert.Description == tlsproto.AlertInappropriateFallback {
+		s.Downgrade.InappropriateFallback = true
+		s.updateDowngrade()
+	}
+}
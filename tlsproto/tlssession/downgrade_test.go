@@ -0,0 +1,87 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlssession
+
+import (
+	"testing"
+
+	"github.com/luisguillenc/tlslayer"
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+// withSentinel returns a copy of the server hello record with the downgrade sentinel in random
+func withSentinel(data []byte, last byte) []byte {
+	cp := append([]byte{}, data...)
+	// record header, handshake header, version and first 24 bytes of random
+	copy(cp[5+4+2+24:], []byte{0x44, 0x4F, 0x57, 0x4E, 0x47, 0x52, 0x44, last})
+	return cp
+}
+
+// withFallbackSCSV returns a copy of the client hello record with the first
+// cipher suite replaced by TLS_FALLBACK_SCSV
+func withFallbackSCSV(data []byte) []byte {
+	cp := append([]byte{}, data...)
+	idx := 5 + 4 + 2 + 32
+	idx += 1 + int(cp[idx]) + 2
+	cp[idx], cp[idx+1] = 0x56, 0x00
+	return cp
+}
+
+func TestDowngrade(t *testing.T) {
+	tests := []struct {
+		records       []testRecord
+		fallback      bool
+		inappropriate bool
+		sentinel      tlslayer.ProtocolVersion
+		detected      bool
+	}{
+		{
+			records: []testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, testRecordMultipleHsk1},
+			},
+		},
+		{
+			// client offers tls 1.3
+			records: []testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, withSentinel(testRecordMultipleHsk1, 0x01)},
+			},
+			sentinel: tlslayer.VersionTLS12,
+			detected: true,
+		},
+		{
+			records: []testRecord{
+				{ClientToServer, withFallbackSCSV(testRecordClientHello1)},
+				{ServerToClient, record(tlslayer.ContentTypeAlert, []byte{0x02, byte(tlsproto.AlertInappropriateFallback)})},
+			},
+			fallback:      true,
+			inappropriate: true,
+			detected:      true,
+		},
+		{
+			// fallback accepted by server
+			records: []testRecord{
+				{ClientToServer, withFallbackSCSV(testRecordClientHello1)},
+				{ServerToClient, testRecordMultipleHsk1},
+			},
+			fallback: true,
+		},
+	}
+	for i, test := range tests {
+		s := NewSession()
+		addRecords(s, test.records, t)
+		r := s.Downgrade
+		if r.FallbackSCSV != test.fallback {
+			t.Errorf("test %d: expected fallback: %v, got: %v", i, test.fallback, r.FallbackSCSV)
+		}
+		if r.InappropriateFallback != test.inappropriate {
+			t.Errorf("test %d: expected inappropriate fallback: %v, got: %v", i, test.inappropriate, r.InappropriateFallback)
+		}
+		if r.Sentinel != test.sentinel {
+			t.Errorf("test %d: expected sentinel: %v, got: %v", i, test.sentinel, r.Sentinel)
+		}
+		if r.Detected != test.detected {
+			t.Errorf("test %d: expected detected: %v, got: %v (%v)", i, test.detected, r.Detected, r)
+		}
+	}
+}
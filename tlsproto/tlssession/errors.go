@@ -0,0 +1,10 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlssession
+
+import "errors"
+
+// Some well known errors
+var (
+	ErrInvalidDirection = errors.New("invalid direction of record")
+)
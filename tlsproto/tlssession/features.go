@@ -0,0 +1,182 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlssession
+
+import (
+	"time"
+
+	"github.com/luisguillenc/tlslayer"
+)
+
+// DefaultFeatureRecords is the number of application data records collected
+// by an extractor created with NewFlowFeatures if maxRecords isn't positive
+const DefaultFeatureRecords = 50
+
+// Histogram sizes, last bin of each histogram stores the values out of range
+const (
+	// LengthBinSize is the width in bytes of the bins of the length histogram
+	LengthBinSize = 1536
+	// LengthBins is the number of bins of the length histogram, the last one
+	// includes the maximum expansion of tls 1.2 records
+	LengthBins = 12
+	// IntervalBins is the number of bins of the inter-arrival histogram, bin i
+	// stores intervals lower than 2^i milliseconds
+	IntervalBins = 16
+)
+
+// RecordFeature is a record of the flow, Interval is the time elapsed since
+// previous record and it's encoded in nanoseconds
+type RecordFeature struct {
+	Direction Direction            `json:"dir"`
+	Type      tlslayer.ContentType `json:"type"`
+	Length    int                  `json:"len"`
+	Interval  time.Duration        `json:"ipt"`
+}
+
+// LengthStats stores statistics of the application data records of a direction
+type LengthStats struct {
+	Records int `json:"records"`
+	Bytes   int `json:"bytes"`
+	Min     int `json:"min"`
+	Max     int `json:"max"`
+}
+
+// Mean returns the mean length of the records
+func (l LengthStats) Mean() float64 {
+	if l.Records == 0 {
+		return 0
+	}
+	return float64(l.Bytes) / float64(l.Records)
+}
+
+func (l *LengthStats) add(length int) {
+	if l.Records == 0 || length < l.Min {
+		l.Min = length
+	}
+	if length > l.Max {
+		l.Max = length
+	}
+	l.Records++
+	l.Bytes += length
+}
+
+// FlowFeatures extracts features of an encrypted flow from the length and
+// timing of its records, without decrypting them. Records of all types are
+// collected until MaxRecords application data records are seen (with a limit
+// of twice MaxRecords records in total), histograms
+// and statistics include all the application data records of the flow.
+type FlowFeatures struct {
+	MaxRecords int `json:"-"`
+
+	Records []RecordFeature `json:"records"`
+	// SPLTLengths and SPLTTimes are the sequence of packet lengths and times of
+	// the application data records collected. Lengths are negative from server
+	// to client and times are the milliseconds since previous one.
+	SPLTLengths []int   `json:"spltLengths"`
+	SPLTTimes   []int64 `json:"spltTimes"`
+
+	// histograms of application data records by direction
+	LengthHistogram   [2][LengthBins]int   `json:"lengthHistogram"`
+	IntervalHistogram [2][IntervalBins]int `json:"intervalHistogram"`
+
+	Client LengthStats `json:"client"`
+	Server LengthStats `json:"server"`
+
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+
+	appData     int
+	last        time.Time
+	lastAppData time.Time
+	lastData    [2]time.Time
+}
+
+// NewFlowFeatures returns an extractor that collects maxRecords application
+// data records, DefaultFeatureRecords is used if maxRecords <= 0
+func NewFlowFeatures(maxRecords int) *FlowFeatures {
+	if maxRecords <= 0 {
+		maxRecords = DefaultFeatureRecords
+	}
+	return &FlowFeatures{
+		MaxRecords:  maxRecords,
+		Records:     make([]RecordFeature, 0, maxRecords),
+		SPLTLengths: make([]int, 0, maxRecords),
+		SPLTTimes:   make([]int64, 0, maxRecords),
+	}
+}
+
+// stats returns the statistics of the direction
+func (f *FlowFeatures) stats(dir Direction) *LengthStats {
+	if dir == ClientToServer {
+		return &f.Client
+	}
+	return &f.Server
+}
+
+// AddRecord updates the features with a record of the direction captured at ts.
+// Records must be added in order of capture.
+func (f *FlowFeatures) AddRecord(dir Direction, tlsr *tlslayer.TLSRecord, ts time.Time) error {
+	if !dir.IsValid() {
+		return ErrInvalidDirection
+	}
+	var interval time.Duration
+	if f.Start.IsZero() {
+		f.Start = ts
+	} else {
+		interval = ts.Sub(f.last)
+	}
+	f.last = ts
+	f.Duration = ts.Sub(f.Start)
+
+	length := int(tlsr.Len)
+	appData := tlsr.Type == tlslayer.ContentTypeApplicationData
+	// other types are limited in flows without application data
+	if f.appData < f.MaxRecords && len(f.Records) < 2*f.MaxRecords {
+		f.Records = append(f.Records, RecordFeature{Direction: dir, Type: tlsr.Type, Length: length, Interval: interval})
+	}
+	if !appData {
+		return nil
+	}
+
+	if f.appData < f.MaxRecords {
+		splt := length
+		if dir == ServerToClient {
+			splt = -length
+		}
+		var ms int64
+		if f.appData > 0 {
+			ms = int64(ts.Sub(f.lastAppData) / time.Millisecond)
+		}
+		f.SPLTLengths = append(f.SPLTLengths, splt)
+		f.SPLTTimes = append(f.SPLTTimes, ms)
+	}
+	f.appData++
+	f.lastAppData = ts
+
+	f.stats(dir).add(length)
+	f.LengthHistogram[dir][lengthBin(length)]++
+	if last := f.lastData[dir]; !last.IsZero() {
+		f.IntervalHistogram[dir][intervalBin(ts.Sub(last))]++
+	}
+	f.lastData[dir] = ts
+	return nil
+}
+
+// lengthBin returns the bin of the length histogram
+func lengthBin(length int) int {
+	bin := length / LengthBinSize
+	if bin >= LengthBins {
+		return LengthBins - 1
+	}
+	return bin
+}
+
+// intervalBin returns the bin of the inter-arrival histogram
+func intervalBin(d time.Duration) int {
+	ms := d / time.Millisecond
+	bin := 0
+	for limit := time.Duration(1); ms >= limit && bin < IntervalBins-1; limit <<= 1 {
+		bin++
+	}
+	return bin
+}
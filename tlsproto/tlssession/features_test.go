@@ -0,0 +1,111 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlssession
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+
+	"github.com/luisguillenc/tlslayer"
+)
+
+func TestFlowFeatures(t *testing.T) {
+	records := []struct {
+		dir  Direction
+		data []byte
+		ms   int
+	}{
+		{ClientToServer, testRecordClientHello1, 0},
+		{ServerToClient, testRecordMultipleHsk1, 30},
+		{ClientToServer, testRecordAppData1, 60},
+		{ServerToClient, record(tlslayer.ContentTypeApplicationData, make([]byte, 2000)), 90},
+		{ClientToServer, testRecordAppData1, 1060},
+		{ClientToServer, testRecordAppData1, 2060},
+	}
+	f := NewFlowFeatures(3)
+	start := time.Unix(1000, 0)
+	for i, r := range records {
+		tlsr := &tlslayer.TLSRecord{}
+		if err := tlsr.DecodeFromBytes(r.data, gopacket.NilDecodeFeedback); err != nil {
+			t.Fatalf("bad tlsrecord %d: %v", i, err)
+		}
+		if err := f.AddRecord(r.dir, tlsr, start.Add(time.Duration(r.ms)*time.Millisecond)); err != nil {
+			t.Fatalf("adding record %d: %v", i, err)
+		}
+	}
+	appLen := len(testRecordAppData1) - 5
+
+	if len(f.Records) != 5 {
+		t.Fatalf("expected records: 5, got: %v", len(f.Records))
+	}
+	if f.Records[1].Type != tlslayer.ContentTypeHandshake || f.Records[1].Interval != 30*time.Millisecond {
+		t.Errorf("unexpected record: %+v", f.Records[1])
+	}
+	wantLengths := []int{appLen, -2000, appLen}
+	wantTimes := []int64{0, 30, 970}
+	if len(f.SPLTLengths) != 3 || len(f.SPLTTimes) != 3 {
+		t.Fatalf("unexpected splt: %v %v", f.SPLTLengths, f.SPLTTimes)
+	}
+	for i := range wantLengths {
+		if f.SPLTLengths[i] != wantLengths[i] || f.SPLTTimes[i] != wantTimes[i] {
+			t.Errorf("expected splt %d: %v %v, got: %v %v", i, wantLengths[i], wantTimes[i], f.SPLTLengths[i], f.SPLTTimes[i])
+		}
+	}
+	if f.Client.Records != 3 || f.Client.Bytes != 3*appLen || f.Server.Records != 1 || f.Server.Max != 2000 {
+		t.Errorf("unexpected stats: %+v %+v", f.Client, f.Server)
+	}
+	if f.LengthHistogram[ServerToClient][1] != 1 || f.LengthHistogram[ClientToServer][lengthBin(appLen)] != 3 {
+		t.Errorf("unexpected length histogram: %v", f.LengthHistogram)
+	}
+	// two intervals of 1000ms in [512,1024)
+	if f.IntervalHistogram[ClientToServer][10] != 2 {
+		t.Errorf("unexpected interval histogram: %v", f.IntervalHistogram)
+	}
+	if f.Duration != 2060*time.Millisecond {
+		t.Errorf("unexpected duration: %v", f.Duration)
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal("encoding json:", err)
+	}
+	var decoded FlowFeatures
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal("decoding json:", err)
+	}
+	if len(decoded.SPLTLengths) != 3 || decoded.Client != f.Client {
+		t.Errorf("unexpected json: %s", data)
+	}
+
+	if err := f.AddRecord(Direction(2), &tlslayer.TLSRecord{}, start); err != ErrInvalidDirection {
+		t.Errorf("expected error: %v, got: %v", ErrInvalidDirection, err)
+	}
+}
+
+func TestNewFlowFeaturesDefault(t *testing.T) {
+	for _, max := range []int{0, -1} {
+		f := NewFlowFeatures(max)
+		if f.MaxRecords != DefaultFeatureRecords || cap(f.Records) != DefaultFeatureRecords {
+			t.Errorf("expected max records: %v, got: %v", DefaultFeatureRecords, f.MaxRecords)
+		}
+	}
+}
+
+func TestIntervalBin(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+		{3 * time.Millisecond, 2},
+		{time.Hour, IntervalBins - 1},
+	}
+	for _, test := range tests {
+		if got := intervalBin(test.d); got != test.want {
+			t.Errorf("expected bin of %v: %v, got: %v", test.d, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlssession
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+)
+
+// DefaultMaxResumptionEntries is the maximum number of session ids and tickets
+// kept by a tracker created with NewResumptionTracker
+const DefaultMaxResumptionEntries = 65536
+
+// resumptionKey identifies a session id or a ticket, tls 1.3 psk identities are
+// the tickets issued by server so they share the key type
+type resumptionKey struct {
+	ticket bool
+	hash   [sha256.Size]byte
+}
+
+func newResumptionKey(ticket bool, data []byte) resumptionKey {
+	return resumptionKey{ticket: ticket, hash: sha256.Sum256(data)}
+}
+
+// resumptionEntry stores where a session id or ticket was issued and the clients that presented it
+type resumptionEntry struct {
+	issued  bool
+	issuer  string
+	clients []string
+}
+
+// addClient adds the client if it isn't in the list
+func (e *resumptionEntry) addClient(client string) {
+	for _, c := range e.clients {
+		if c == client {
+			return
+		}
+	}
+	e.clients = append(e.clients, client)
+}
+
+// crossClient returns true if the entry was used by a client different of the issuer or by several clients
+func (e *resumptionEntry) crossClient() bool {
+	if len(e.clients) > 1 {
+		return true
+	}
+	return e.issued && len(e.clients) == 1 && e.clients[0] != e.issuer
+}
+
+// ResumptionEvent is the result of tracking a connection
+type ResumptionEvent struct {
+	Client string         `json:"client"`
+	Type   ResumptionType `json:"type"`
+	// Known is true if the session id or ticket was issued in a connection seen by the tracker
+	Known bool `json:"known"`
+	// Issuer is the client of the connection where the session id or ticket was issued
+	Issuer string `json:"issuer,omitempty"`
+	// Clients are the different clients that presented the session id or ticket
+	Clients []string `json:"clients,omitempty"`
+	// CrossClient is true if a session id or ticket presented was used by
+	// other clients, it's an indicator of session hijacking
+	CrossClient bool `json:"crossClient"`
+}
+
+func (e ResumptionEvent) String() string {
+	return fmt.Sprintf("%s %s known=%v issuer=%q clients=%q cross=%v", e.Client, e.Type, e.Known, e.Issuer, e.Clients, e.CrossClient)
+}
+
+// ResumptionTracker correlates session ids and tickets across connections. It
+// isn't safe for concurrent use.
+type ResumptionTracker struct {
+	// MaxEntries is the maximum number of session ids and tickets kept, when
+	// it's reached the tracker is flushed. Zero means no limit.
+	MaxEntries int
+
+	entries map[resumptionKey]*resumptionEntry
+}
+
+// NewResumptionTracker returns a tracker using the default maximum of entries
+func NewResumptionTracker() *ResumptionTracker {
+	return &ResumptionTracker{
+		MaxEntries: DefaultMaxResumptionEntries,
+		entries:    make(map[resumptionKey]*resumptionEntry),
+	}
+}
+
+// Len returns the number of session ids and tickets tracked
+func (t *ResumptionTracker) Len() int {
+	return len(t.entries)
+}
+
+// entry returns the entry of the key creating it if it doesn't exist
+func (t *ResumptionTracker) entry(key resumptionKey) *resumptionEntry {
+	if t.entries == nil {
+		t.entries = make(map[resumptionKey]*resumptionEntry)
+	}
+	e, ok := t.entries[key]
+	if ok {
+		return e
+	}
+	if t.MaxEntries > 0 && len(t.entries) >= t.MaxEntries {
+		t.entries = make(map[resumptionKey]*resumptionEntry)
+	}
+	e = &resumptionEntry{}
+	t.entries[key] = e
+	return e
+}
+
+// Track updates the tracker with the session of the client and returns the
+// classification of the connection
+func (t *ResumptionTracker) Track(client net.IP, s *Session) ResumptionEvent {
+	clientID := client.String()
+	ev := ResumptionEvent{Client: clientID, Type: s.Resumption}
+
+	// session ids and tickets presented by client, first is the one used to resume
+	var presented []resumptionKey
+	if ch := s.ClientHello; ch != nil {
+		if s.Resumption == ResumptionSessionID && len(ch.SessionID) > 0 {
+			presented = append(presented, newResumptionKey(false, ch.SessionID))
+		}
+		if info := ch.ExtInfo; info != nil {
+			if len(info.SessionTicketData) > 0 {
+				presented = append(presented, newResumptionKey(true, info.SessionTicketData))
+			}
+			selected := -1
+			if s.Resumption == ResumptionPSK && s.ServerHello != nil && s.ServerHello.ExtInfo != nil {
+				selected = int(s.ServerHello.ExtInfo.PSKSelectedIdentity)
+			}
+			for i, psk := range info.PSKIdentities {
+				key := newResumptionKey(true, psk.Identity)
+				if i == selected {
+					presented = append([]resumptionKey{key}, presented...)
+					continue
+				}
+				presented = append(presented, key)
+			}
+		}
+	}
+	for i, key := range presented {
+		e := t.entry(key)
+		e.addClient(clientID)
+		if e.crossClient() {
+			ev.CrossClient = true
+		}
+		if i == 0 {
+			ev.Known = e.issued
+			ev.Issuer = e.issuer
+			ev.Clients = append([]string(nil), e.clients...)
+		}
+	}
+
+	// session ids and tickets issued by server
+	if sh := s.ServerHello; sh != nil && s.Resumption == ResumptionNone && !s.IsTLS13() && len(sh.SessionID) > 0 {
+		t.issue(newResumptionKey(false, sh.SessionID), clientID)
+	}
+	for _, nst := range s.NewSessionTickets {
+		if len(nst.Ticket) > 0 {
+			t.issue(newResumptionKey(true, nst.Ticket), clientID)
+		}
+	}
+	return ev
+}
+
+// issue marks the key as issued to the client
+func (t *ResumptionTracker) issue(key resumptionKey, client string) {
+	e := t.entry(key)
+	if !e.issued {
+		e.issued = true
+		e.issuer = client
+	}
+	e.addClient(client)
+}
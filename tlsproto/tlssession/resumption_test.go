@@ -0,0 +1,88 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlssession
+
+import (
+	"net"
+	"testing"
+
+	"github.com/luisguillenc/tlslayer"
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+var testTicket1 = []byte{0x01, 0x02, 0x03, 0x04}
+
+// newSessionTicket12 builds a tls 1.2 new session ticket record with the ticket
+func newSessionTicket12(ticket []byte) []byte {
+	body := []byte{0x00, 0x00, 0x1c, 0x20, byte(len(ticket) >> 8), byte(len(ticket))}
+	body = append(body, ticket...)
+	hsk := []byte{byte(tlsproto.HandshakeTypeNewSessionTicket), 0x00, byte(len(body) >> 8), byte(len(body))}
+	return record(tlslayer.ContentTypeHandshake, append(hsk, body...))
+}
+
+// resumedSession returns a session resumed by the client with the ticket
+func resumedSession(resumption ResumptionType, ticket []byte) *Session {
+	s := NewSession()
+	s.Resumption = resumption
+	s.ClientHello = &tlsproto.ClientHelloData{ExtInfo: &tlsproto.ExtensionsInfo{}}
+	s.ServerHello = &tlsproto.ServerHelloData{ExtInfo: &tlsproto.ExtensionsInfo{}}
+	switch resumption {
+	case ResumptionTicket:
+		s.ClientHello.ExtInfo.SessionTicket = true
+		s.ClientHello.ExtInfo.SessionTicketData = ticket
+	case ResumptionPSK:
+		s.Version = tlslayer.VersionTLS13
+		s.ClientHello.ExtInfo.PSKIdentities = []tlsproto.PSKIdentity{{Identity: []byte{0xff}}, {Identity: ticket}}
+		s.ServerHello.ExtInfo.PSKSelectedIdentity = 1
+	}
+	return s
+}
+
+func TestResumptionTracker(t *testing.T) {
+	client1 := net.ParseIP("10.0.0.1")
+	client2 := net.ParseIP("10.0.0.2")
+	tracker := NewResumptionTracker()
+
+	// full handshake issuing a ticket
+	s := NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, testRecordMultipleHsk1},
+		{ServerToClient, newSessionTicket12(testTicket1)},
+	}, t)
+	if len(s.NewSessionTickets) != 1 {
+		t.Fatalf("expected tickets: 1, got: %v", len(s.NewSessionTickets))
+	}
+	ev := tracker.Track(client1, s)
+	if ev.Type != ResumptionNone || ev.Known || ev.CrossClient {
+		t.Errorf("unexpected event: %v", ev)
+	}
+
+	// resumed by the same client
+	ev = tracker.Track(client1, resumedSession(ResumptionTicket, testTicket1))
+	if ev.Type != ResumptionTicket || !ev.Known || ev.Issuer != client1.String() || ev.CrossClient {
+		t.Errorf("unexpected event: %v", ev)
+	}
+
+	// resumed by other client
+	ev = tracker.Track(client2, resumedSession(ResumptionTicket, testTicket1))
+	if !ev.Known || !ev.CrossClient || len(ev.Clients) != 2 {
+		t.Errorf("expected cross client reuse: %v", ev)
+	}
+
+	// psk identities not issued in a connection seen
+	ticket := []byte{0x0a, 0x0b}
+	ev = tracker.Track(client1, resumedSession(ResumptionPSK, ticket))
+	if ev.Type != ResumptionPSK || ev.Known || ev.CrossClient {
+		t.Errorf("unexpected event: %v", ev)
+	}
+	ev = tracker.Track(client2, resumedSession(ResumptionPSK, ticket))
+	if !ev.CrossClient || len(ev.Clients) != 2 || ev.Clients[0] != client1.String() {
+		t.Errorf("expected cross client reuse: %v", ev)
+	}
+
+	tracker.MaxEntries = tracker.Len()
+	tracker.Track(client1, resumedSession(ResumptionTicket, []byte{0x0c}))
+	if tracker.Len() != 1 {
+		t.Errorf("expected tracker flushed, got: %v entries", tracker.Len())
+	}
+}
@@ -0,0 +1,435 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlssession
+
+import (
+	"fmt"
+
+	"github.com/luisguillenc/tlslayer"
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+// Direction is the direction of a record in the flow
+type Direction uint8
+
+// Direction possible values
+const (
+	ClientToServer Direction = 0
+	ServerToClient Direction = 1
+)
+
+func (d Direction) getDesc() string {
+	switch d {
+	case ClientToServer:
+		return "client_to_server"
+	case ServerToClient:
+		return "server_to_client"
+	default:
+		return "unknown"
+	}
+}
+
+func (d Direction) String() string {
+	return fmt.Sprintf("%s(%d)", d.getDesc(), d)
+}
+
+// IsValid method checks if it's a valid value
+func (d Direction) IsValid() bool {
+	return d == ClientToServer || d == ServerToClient
+}
+
+// ResumptionType is the way a session was established
+type ResumptionType uint8
+
+// ResumptionType possible values
+const (
+	ResumptionNone      ResumptionType = 0
+	ResumptionSessionID ResumptionType = 1
+	ResumptionTicket    ResumptionType = 2
+	ResumptionPSK       ResumptionType = 3
+)
+
+func (r ResumptionType) getDesc() string {
+	switch r {
+	case ResumptionNone:
+		return "full_handshake"
+	case ResumptionSessionID:
+		return "session_id"
+	case ResumptionTicket:
+		return "session_ticket"
+	case ResumptionPSK:
+		return "psk"
+	default:
+		return "unknown"
+	}
+}
+
+func (r ResumptionType) String() string {
+	return fmt.Sprintf("%s(%d)", r.getDesc(), r)
+}
+
+// AlertEvent stores an alert seen in the session, encrypted alerts have no level or description
+type AlertEvent struct {
+	Direction   Direction                 `json:"direction"`
+	Level       tlsproto.AlertLevel       `json:"level,omitempty"`
+	Description tlsproto.AlertDescription `json:"description,omitempty"`
+	Encrypted   bool                      `json:"encrypted"`
+}
+
+func (a AlertEvent) String() string {
+	if a.Encrypted {
+		return fmt.Sprintf("%s encrypted", a.Direction)
+	}
+	return fmt.Sprintf("%s %s,%s", a.Direction, a.Level, a.Description)
+}
+
+// HeartbeatEvent stores a heartbeat seen in the session, encrypted, malformed
+// and truncated heartbeats only have the length of the record
+type HeartbeatEvent struct {
+	Direction     Direction                     `json:"direction"`
+	Type          tlsproto.HeartbeatMessageType `json:"type,omitempty"`
+	PayloadLength uint16                        `json:"payloadLength,omitempty"`
+	Length        int                           `json:"length"`
+	Encrypted     bool                          `json:"encrypted"`
+	// Malformed is true if plaintext message can't be decoded
+	Malformed bool `json:"malformed,omitempty"`
+	// Truncated is true if payload of the record wasn't captured completely
+	Truncated bool `json:"truncated,omitempty"`
+	// Heartbleed is true if request declares a payload greater than the record
+	Heartbleed bool `json:"heartbleed"`
+}
+
+func (h HeartbeatEvent) String() string {
+	switch {
+	case h.Encrypted:
+		return fmt.Sprintf("%s encrypted (len=%d)", h.Direction, h.Length)
+	case h.Malformed:
+		return fmt.Sprintf("%s malformed (len=%d)", h.Direction, h.Length)
+	case h.Truncated:
+		return fmt.Sprintf("%s truncated (len=%d)", h.Direction, h.Length)
+	}
+	return fmt.Sprintf("%s %s (payload_length=%d len=%d heartbleed=%v)", h.Direction, h.Type, h.PayloadLength, h.Length, h.Heartbleed)
+}
+
+// Counters stores the records and bytes seen in a direction
+type Counters struct {
+	Records  int `json:"records"`
+	Bytes    int `json:"bytes"`
+	AppData  int `json:"appData"`
+	AppBytes int `json:"appBytes"`
+	// Oversized is the number of records exceeding the negotiated record size limit
+	Oversized int `json:"oversized,omitempty"`
+}
+
+// Session is a summary of a tls connection built from the records of both
+// directions. The zero value is ready to use with default decode options.
+type Session struct {
+	// Options used to decode handshake messages, if they are zero when the
+	// first record is added they are set to tlsproto.DefaultDecodeOptions
+	Options tlsproto.DecodeOptions `json:"-"`
+
+	OfferedVersion tlslayer.ProtocolVersion `json:"offeredVersion"`
+	Version        tlslayer.ProtocolVersion `json:"version"`
+	CipherSuite    tlsproto.CipherSuite     `json:"cipherSuite"`
+	SNI            string                   `json:"sni,omitempty"`
+	ALPNOffered    []string                 `json:"alpnOffered,omitempty"`
+	ALPN           string                   `json:"alpn,omitempty"`
+	HelloRetry     bool                     `json:"helloRetry"`
+	Resumption     ResumptionType           `json:"resumption"`
+	Alerts         []AlertEvent             `json:"alerts,omitempty"`
+	Heartbeats     []HeartbeatEvent         `json:"heartbeats,omitempty"`
+	Client         Counters                 `json:"client"`
+	Server         Counters                 `json:"server"`
+	Complete       bool                     `json:"complete"`
+	Anomalies      []Anomaly                `json:"anomalies,omitempty"`
+	Downgrade      DowngradeReport          `json:"downgrade"`
+
+	ClientHello  *tlsproto.ClientHelloData `json:"clientHello,omitempty"`
+	ServerHello  *tlsproto.ServerHelloData `json:"serverHello,omitempty"`
+	Certificates *tlsproto.CertificateData `json:"certificates,omitempty"`
+	// NewSessionTickets are the tickets issued by server, in tls 1.3 they're protected
+	NewSessionTickets []*tlsproto.NewSessionTicketData `json:"newSessionTickets,omitempty"`
+
+	validator *Validator
+	// state by direction
+	buffer    [2][]byte
+	encrypted [2]bool
+	ccs       [2]bool
+	finished  [2]bool
+	limits    [2]uint16
+}
+
+// NewSession returns an empty session using default decode options
+func NewSession() *Session {
+	return &Session{Options: tlsproto.DefaultDecodeOptions(), validator: NewValidator()}
+}
+
+func (s *Session) String() string {
+	str := fmt.Sprintf("Version: %v (offered %v)\n", s.Version, s.OfferedVersion)
+	str += fmt.Sprintf("Cipher Suite: %v\n", s.CipherSuite)
+	str += fmt.Sprintf("SNI: %q\n", s.SNI)
+	str += fmt.Sprintf("ALPN: %q (offered %q)\n", s.ALPN, s.ALPNOffered)
+	str += fmt.Sprintf("Resumption: %v\n", s.Resumption)
+	str += fmt.Sprintf("Alerts: %v\n", s.Alerts)
+	str += fmt.Sprintf("Heartbeats: %v\n", s.Heartbeats)
+	str += fmt.Sprintf("Client: %+v\n", s.Client)
+	str += fmt.Sprintf("Server: %+v\n", s.Server)
+	str += fmt.Sprintln("Complete:", s.Complete)
+	str += fmt.Sprintf("Anomalies: %v\n", s.Anomalies)
+	str += fmt.Sprintf("Downgrade: %v\n", s.Downgrade)
+
+	return str
+}
+
+// IsTLS13 returns true if negotiated version is tls 1.3 or dtls 1.3
+func (s *Session) IsTLS13() bool {
+	return s.Version.TLSVersion() == tlslayer.VersionTLS13
+}
+
+// Heartbleed returns true if a heartbeat request exploiting Heartbleed was seen
+func (s *Session) Heartbleed() bool {
+	for _, h := range s.Heartbeats {
+		if h.Heartbleed {
+			return true
+		}
+	}
+	return false
+}
+
+// counters returns the counters of the direction
+func (s *Session) counters(dir Direction) *Counters {
+	if dir == ClientToServer {
+		return &s.Client
+	}
+	return &s.Server
+}
+
+// AddRecord updates the session with a record of the direction. Handshake
+// messages fragmented in several records are reassembled. Limit and Oversized
+// of protected records are set if hellos negotiated a record size limit.
+func (s *Session) AddRecord(dir Direction, tlsr *tlslayer.TLSRecord) error {
+	if !dir.IsValid() {
+		return ErrInvalidDirection
+	}
+	if s.validator == nil {
+		s.validator = NewValidator()
+		if s.Options == (tlsproto.DecodeOptions{}) {
+			s.Options = tlsproto.DefaultDecodeOptions()
+		}
+	}
+	defer func() { s.Anomalies = s.validator.Anomalies }()
+	c := s.counters(dir)
+	c.Records++
+	c.Bytes += int(tlsr.Len) + 5
+	// unprotected handshake messages aren't subject to the limits (rfc8449)
+	if s.encrypted[dir] && s.limits[dir] > 0 {
+		tlsr.Limit = s.limits[dir]
+		tlsr.Oversized = tlsr.Len > tlsr.Limit
+		if tlsr.Oversized {
+			c.Oversized++
+		}
+	}
+
+	switch tlsr.Type {
+	case tlslayer.ContentTypeHandshake:
+		if s.encrypted[dir] && !isPlainHandshake(tlsr.Payload()) {
+			// finished message after change_cipher_spec
+			s.validator.Encrypted(dir, tlsr.Type)
+			s.finished[dir] = true
+			s.checkComplete()
+			return nil
+		}
+		return s.addHandshakeData(dir, tlsr.Payload())
+	case tlslayer.ContentTypeChangeCipherSpec:
+		s.validator.ChangeCipherSpec(dir)
+		s.ccs[dir] = true
+		if s.ServerHello != nil && !s.IsTLS13() {
+			s.encrypted[dir] = true
+			if dir == ServerToClient {
+				s.checkAbbreviated()
+			}
+		}
+	case tlslayer.ContentTypeAlert:
+		alert, err := tlsproto.NewAlertFromRecord(tlsr)
+		if err != nil || s.encrypted[dir] {
+			s.Alerts = append(s.Alerts, AlertEvent{Direction: dir, Encrypted: true})
+			return nil
+		}
+		s.Alerts = append(s.Alerts, AlertEvent{Direction: dir, Level: alert.Level, Description: alert.Description})
+		s.addDowngradeAlert(dir, alert)
+	case tlslayer.ContentTypeHeartbeat:
+		s.addHeartbeat(dir, tlsr)
+	case tlslayer.ContentTypeApplicationData:
+		s.validator.Encrypted(dir, tlsr.Type)
+		c.AppData++
+		c.AppBytes += int(tlsr.Len)
+		if s.IsTLS13() {
+			// in tls 1.3 handshake is protected and sent as application data
+			s.finished[dir] = true
+			s.checkComplete()
+		}
+	}
+	return nil
+}
+
+// addHeartbeat adds the heartbeat event of the record, it's only decoded if it isn't protected
+func (s *Session) addHeartbeat(dir Direction, tlsr *tlslayer.TLSRecord) {
+	ev := HeartbeatEvent{Direction: dir, Length: int(tlsr.Len), Encrypted: s.encrypted[dir] || s.IsTLS13()}
+	// payload length is compared with the length declared by the record
+	if !ev.Encrypted && len(tlsr.Payload()) != int(tlsr.Len) {
+		ev.Truncated = true
+	}
+	if !ev.Encrypted && !ev.Truncated {
+		hb := &tlsproto.Heartbeat{}
+		err := hb.DecodeFromBytes(tlsr.Payload(), nil)
+		if err != nil && err != tlsproto.ErrHeartbeatBadLength {
+			ev.Malformed = true
+		} else {
+			ev.Type = hb.Type
+			ev.PayloadLength = hb.PayloadLength
+			ev.Heartbleed = hb.Type == tlsproto.HeartbeatRequest && int(hb.PayloadLength) > ev.Length-3
+		}
+	}
+	s.Heartbeats = append(s.Heartbeats, ev)
+}
+
+// addHandshakeData appends data to the buffer of the direction and decodes
+// the messages completed
+func (s *Session) addHandshakeData(dir Direction, data []byte) error {
+	// data is copied so records can be reused by caller
+	s.buffer[dir] = append(s.buffer[dir], data...)
+	for len(s.buffer[dir]) >= 4 {
+		buf := s.buffer[dir]
+		_, hlen, err := tlsproto.ReadHandshakeHeader(buf)
+		if err != nil {
+			s.buffer[dir] = nil
+			return err
+		}
+		if s.Options.MaxHandshakeSize > 0 && hlen > s.Options.MaxHandshakeSize {
+			s.buffer[dir] = nil
+			return tlsproto.ErrHandshakeTooLarge
+		}
+		if len(buf)-4 < int(hlen) {
+			// wait for next record
+			return nil
+		}
+		s.buffer[dir] = buf[4+hlen:]
+		hsk, err := tlsproto.NewHandshakeFromBytesWithOptions(buf[:4+hlen], s.Options)
+		if err != nil {
+			return err
+		}
+		s.addHandshake(dir, hsk)
+	}
+	if len(s.buffer[dir]) == 0 {
+		s.buffer[dir] = nil
+	}
+	return nil
+}
+
+// addHandshake updates the session with a decoded handshake message
+func (s *Session) addHandshake(dir Direction, hsk *tlsproto.Handshake) {
+	s.validator.Handshake(dir, hsk)
+	switch {
+	case hsk.ClientHello != nil && dir == ClientToServer:
+		ch := hsk.ClientHello
+		s.ClientHello = ch
+		s.OfferedVersion = ch.MaxOfferedVersion()
+		s.Downgrade.FallbackSCSV = ch.FallbackSCSV()
+		if ch.ExtInfo != nil {
+			s.SNI = ch.ExtInfo.SNI
+			s.ALPNOffered = ch.ExtInfo.ALPNs
+		}
+	case hsk.ServerHello != nil && dir == ServerToClient:
+		sh := hsk.ServerHello
+		if sh.IsHelloRetryRequest() {
+			s.HelloRetry = true
+			return
+		}
+		s.ServerHello = sh
+		s.Version = sh.NegotiatedVersion()
+		s.CipherSuite = sh.CipherSuiteSel
+		if sh.ExtInfo != nil && len(sh.ExtInfo.ALPNs) > 0 {
+			s.ALPN = sh.ExtInfo.ALPNs[0]
+		}
+		s.Resumption = s.getResumption()
+		s.Downgrade.Sentinel = sh.DowngradeSentinel()
+		s.updateDowngrade()
+		toClient, toServer := tlsproto.RecordSizeLimits(s.ClientHello, sh)
+		s.limits[ServerToClient], s.limits[ClientToServer] = toClient, toServer
+		if s.IsTLS13() {
+			// rest of the handshake is protected
+			s.encrypted[ClientToServer] = true
+			s.encrypted[ServerToClient] = true
+		}
+	case hsk.Certificate != nil && dir == ServerToClient:
+		s.Certificates = hsk.Certificate
+	case hsk.NewSessionTicket != nil && dir == ServerToClient:
+		s.NewSessionTickets = append(s.NewSessionTickets, hsk.NewSessionTicket)
+	}
+}
+
+// getResumption returns the resumption type that can be deduced from the hellos
+func (s *Session) getResumption() ResumptionType {
+	ch, sh := s.ClientHello, s.ServerHello
+	if ch == nil || sh == nil {
+		return ResumptionNone
+	}
+	if s.IsTLS13() {
+		for _, e := range sh.Extensions {
+			if e.Type == tlsproto.ExtPreSharedKey {
+				return ResumptionPSK
+			}
+		}
+		return ResumptionNone
+	}
+	// server echoes the session id when it resumes the session
+	if len(sh.SessionID) > 0 && string(sh.SessionID) == string(ch.SessionID) {
+		if offeredTicket(ch) {
+			return ResumptionTicket
+		}
+		return ResumptionSessionID
+	}
+	return ResumptionNone
+}
+
+// checkAbbreviated sets resumption if server sends change_cipher_spec without certificate
+func (s *Session) checkAbbreviated() {
+	if s.Resumption != ResumptionNone || s.Certificates != nil {
+		return
+	}
+	if offeredTicket(s.ClientHello) {
+		s.Resumption = ResumptionTicket
+	}
+}
+
+// checkComplete sets the session completed when both directions finished the handshake
+func (s *Session) checkComplete() {
+	if s.ServerHello == nil {
+		return
+	}
+	if s.IsTLS13() {
+		// client sends protected records only after server flight
+		s.Complete = s.finished[ClientToServer] && s.finished[ServerToClient]
+		return
+	}
+	s.Complete = s.ccs[ClientToServer] && s.ccs[ServerToClient] &&
+		s.finished[ClientToServer] && s.finished[ServerToClient]
+}
+
+// isPlainHandshake returns true if data is a sequence of complete handshake messages,
+// it's used to find plaintext messages where protected ones were expected
+func isPlainHandshake(data []byte) bool {
+	for len(data) > 0 {
+		_, hlen, err := tlsproto.ReadHandshakeHeader(data)
+		if err != nil || len(data)-4 < int(hlen) {
+			return false
+		}
+		data = data[4+hlen:]
+	}
+	return true
+}
+
+// offeredTicket returns true if client sent a non empty session ticket
+func offeredTicket(ch *tlsproto.ClientHelloData) bool {
+	return ch != nil && ch.ExtInfo != nil && ch.ExtInfo.SessionTicketLen > 0
+}
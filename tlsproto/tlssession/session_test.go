@@ -0,0 +1,315 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlssession
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	"github.com/google/gopacket"
+
+	"github.com/luisguillenc/tlslayer"
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+const (
+	pathBinFiles = "../../test/data"
+)
+
+func loadBinFile(bindata *[]byte, binfile string) error {
+	file, err := os.Open(pathBinFiles + "/" + binfile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stats, statsErr := file.Stat()
+	if statsErr != nil {
+		return statsErr
+	}
+
+	size := stats.Size()
+	*bindata = make([]byte, size)
+
+	bufr := bufio.NewReader(file)
+	_, err = bufr.Read(*bindata)
+
+	return err
+}
+
+var testRecordClientHello1 []byte
+var testRecordMultipleHsk1 []byte
+var testRecordCertificate1 []byte
+var testRecordCCS1 []byte
+var testRecordEncrypted1 []byte
+var testRecordAppData1 []byte
+
+func init() {
+	var loadFiles = []struct {
+		vardata *[]byte
+		binfile string
+	}{
+		{&testRecordClientHello1, "tlsr-hsk-clienthello1.bin"},
+		{&testRecordMultipleHsk1, "tlsr-hsk-multiple1.bin"},
+		{&testRecordCertificate1, "tlsr-hsk-certificate1.bin"},
+		{&testRecordCCS1, "tlsr-ccs1.bin"},
+		{&testRecordEncrypted1, "tlsr-hsk-encrypted1.bin"},
+		{&testRecordAppData1, "tlsr-appdata1.bin"},
+	}
+	for _, f := range loadFiles {
+		err := loadBinFile(f.vardata, f.binfile)
+		if err != nil {
+			panic("unable to load " + f.binfile)
+		}
+	}
+}
+
+// record builds a tls record of the type with the payload
+func record(ctype tlslayer.ContentType, payload []byte) []byte {
+	data := []byte{byte(ctype), 0x03, 0x03, byte(len(payload) >> 8), byte(len(payload))}
+	return append(data, payload...)
+}
+
+// serverHello13 builds a tls 1.3 server hello record, with psk extension if psk is true
+func serverHello13(psk bool) []byte {
+	exts := []byte{
+		0x00, 0x2b, 0x00, 0x02, 0x03, 0x04, // supported_versions
+		0x00, 0x33, 0x00, 0x24, 0x00, 0x1d, 0x00, 0x20, // key_share
+	}
+	exts = append(exts, make([]byte, 32)...)
+	if psk {
+		exts = append(exts, 0x00, 0x29, 0x00, 0x02, 0x00, 0x00)
+	}
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 0x00)                // session id
+	body = append(body, 0x13, 0x01, 0x00)    // cipher suite and compression
+	body = append(body, byte(len(exts)>>8), byte(len(exts)))
+	body = append(body, exts...)
+	hsk := []byte{0x02, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	return record(tlslayer.ContentTypeHandshake, append(hsk, body...))
+}
+
+// hello12 builds a tls 1.2 hello record of the handshake type with the extensions
+func hello12(htype tlsproto.HandshakeType, exts []byte) []byte {
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 0x00)                // session id
+	if htype == tlsproto.HandshakeTypeClientHello {
+		body = append(body, 0x00, 0x02, 0xc0, 0x2f, 0x01, 0x00) // cipher suites and compression methods
+	} else {
+		body = append(body, 0xc0, 0x2f, 0x00) // cipher suite and compression
+	}
+	body = append(body, byte(len(exts)>>8), byte(len(exts)))
+	body = append(body, exts...)
+	hsk := []byte{byte(htype), byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	return record(tlslayer.ContentTypeHandshake, append(hsk, body...))
+}
+
+type testRecord struct {
+	dir  Direction
+	data []byte
+}
+
+func addRecords(s *Session, records []testRecord, t *testing.T) {
+	for i, r := range records {
+		tlsr := &tlslayer.TLSRecord{}
+		if err := tlsr.DecodeFromBytes(r.data, gopacket.NilDecodeFeedback); err != nil {
+			t.Fatalf("bad tlsrecord %d: %v", i, err)
+		}
+		if err := s.AddRecord(r.dir, tlsr); err != nil {
+			t.Fatalf("adding record %d: %v", i, err)
+		}
+	}
+}
+
+func TestSessionTLS12(t *testing.T) {
+	// zero value uses default decode options
+	for _, s := range []*Session{NewSession(), {}} {
+		addRecords(s, []testRecord{
+			{ClientToServer, testRecordClientHello1},
+			{ServerToClient, testRecordMultipleHsk1},
+			{ClientToServer, testRecordCCS1},
+			{ClientToServer, testRecordEncrypted1},
+			{ServerToClient, testRecordCCS1},
+			{ServerToClient, testRecordEncrypted1},
+			{ClientToServer, testRecordAppData1},
+		}, t)
+
+		if s.OfferedVersion != tlslayer.VersionTLS13 {
+			t.Errorf("expected offered version: %v, got: %v", tlslayer.VersionTLS13, s.OfferedVersion)
+		}
+		if s.Version != tlslayer.VersionTLS12 {
+			t.Errorf("expected version: %v, got: %v", tlslayer.VersionTLS12, s.Version)
+		}
+		if s.CipherSuite != tlsproto.CipherSuite(0x002f) {
+			t.Errorf("unexpected cipher suite: %v", s.CipherSuite)
+		}
+		if s.SNI != "tiles.services.mozilla.com" {
+			t.Errorf("unexpected sni: %v", s.SNI)
+		}
+		if len(s.ALPNOffered) != 2 || s.ALPN != "" {
+			t.Errorf("unexpected alpn: %v %v", s.ALPNOffered, s.ALPN)
+		}
+		if s.Certificates == nil || len(s.Certificates.Certificates) != 2 {
+			t.Error("expected certificate chain")
+		}
+		if s.Resumption != ResumptionNone {
+			t.Errorf("expected resumption: %v, got: %v", ResumptionNone, s.Resumption)
+		}
+		if !s.Complete {
+			t.Error("expected session completed")
+		}
+		if s.Client.Records != 4 || s.Client.AppData != 1 || s.Client.Bytes != len(testRecordClientHello1)+len(testRecordCCS1)+len(testRecordEncrypted1)+len(testRecordAppData1) {
+			t.Errorf("unexpected client counters: %+v", s.Client)
+		}
+		if s.Server.Records != 3 || s.Server.AppData != 0 {
+			t.Errorf("unexpected server counters: %+v", s.Server)
+		}
+	}
+}
+
+func TestSessionIncomplete(t *testing.T) {
+	s := NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, testRecordMultipleHsk1},
+		{ClientToServer, record(tlslayer.ContentTypeAlert, []byte{0x02, 0x2a})},
+	}, t)
+	if s.Complete {
+		t.Error("unexpected session completed")
+	}
+	if len(s.Alerts) != 1 || s.Alerts[0].Description != tlsproto.AlertBadCertificate || s.Alerts[0].Encrypted {
+		t.Errorf("unexpected alerts: %v", s.Alerts)
+	}
+}
+
+func TestSessionReassembly(t *testing.T) {
+	payload := testRecordClientHello1[5:]
+	s := NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, record(tlslayer.ContentTypeHandshake, payload[:100])},
+		{ClientToServer, record(tlslayer.ContentTypeHandshake, payload[100:])},
+	}, t)
+	if s.ClientHello == nil {
+		t.Fatal("expected clienthello reassembled")
+	}
+	if s.SNI != "tiles.services.mozilla.com" {
+		t.Errorf("unexpected sni: %v", s.SNI)
+	}
+}
+
+func TestSessionTLS13(t *testing.T) {
+	tests := []struct {
+		psk  bool
+		want ResumptionType
+	}{
+		{false, ResumptionNone},
+		{true, ResumptionPSK},
+	}
+	for _, test := range tests {
+		s := NewSession()
+		addRecords(s, []testRecord{
+			{ClientToServer, testRecordClientHello1},
+			{ServerToClient, serverHello13(test.psk)},
+			{ServerToClient, testRecordCCS1},
+			{ServerToClient, testRecordAppData1},
+		}, t)
+		if s.Version != tlslayer.VersionTLS13 {
+			t.Errorf("expected version: %v, got: %v", tlslayer.VersionTLS13, s.Version)
+		}
+		if s.Resumption != test.want {
+			t.Errorf("expected resumption: %v, got: %v", test.want, s.Resumption)
+		}
+		if s.Complete {
+			t.Error("unexpected session completed before client finished")
+		}
+		addRecords(s, []testRecord{
+			{ClientToServer, testRecordCCS1},
+			{ClientToServer, testRecordAppData1},
+		}, t)
+		if !s.Complete {
+			t.Error("expected session completed")
+		}
+	}
+}
+
+func TestSessionRecordSizeLimit(t *testing.T) {
+	s := NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, hello12(tlsproto.HandshakeTypeClientHello, []byte{0x00, 0x1c, 0x00, 0x02, 0x02, 0x00})},
+		{ServerToClient, hello12(tlsproto.HandshakeTypeServerHello, []byte{0x00, 0x1c, 0x00, 0x02, 0x04, 0x00})},
+		{ClientToServer, record(tlslayer.ContentTypeHandshake, make([]byte, 4000))},
+		{ClientToServer, testRecordCCS1},
+		{ServerToClient, testRecordCCS1},
+	}, t)
+	tests := []struct {
+		dir       Direction
+		len       int
+		limit     uint16
+		oversized bool
+	}{
+		{ClientToServer, 3072, 1024 + tlslayer.MaxTLS12RecordExpansion, false},
+		{ClientToServer, 3073, 1024 + tlslayer.MaxTLS12RecordExpansion, true},
+		{ServerToClient, 2561, 512 + tlslayer.MaxTLS12RecordExpansion, true},
+	}
+	for _, test := range tests {
+		tlsr := &tlslayer.TLSRecord{}
+		if err := tlsr.DecodeFromBytes(record(tlslayer.ContentTypeApplicationData, make([]byte, test.len)), gopacket.NilDecodeFeedback); err != nil {
+			t.Fatal("bad tlsrecord:", err)
+		}
+		if err := s.AddRecord(test.dir, tlsr); err != nil {
+			t.Fatal("adding record:", err)
+		}
+		if tlsr.Limit != test.limit || tlsr.Oversized != test.oversized {
+			t.Errorf("expected limit: %v oversized: %v, got: %v %v", test.limit, test.oversized, tlsr.Limit, tlsr.Oversized)
+		}
+	}
+	// unprotected handshake record isn't limited
+	if s.Client.Oversized != 1 || s.Server.Oversized != 1 {
+		t.Errorf("expected oversized: 1 1, got: %v %v", s.Client.Oversized, s.Server.Oversized)
+	}
+}
+
+func TestSessionHeartbeat(t *testing.T) {
+	s := NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, testRecordMultipleHsk1},
+		{ClientToServer, record(tlslayer.ContentTypeHeartbeat, []byte{0x01, 0x40, 0x00})},
+	}, t)
+	if len(s.Heartbeats) != 1 || !s.Heartbeats[0].Heartbleed || s.Heartbeats[0].PayloadLength != 16384 {
+		t.Errorf("unexpected heartbeats: %v", s.Heartbeats)
+	}
+	if !s.Heartbleed() {
+		t.Error("expected heartbleed")
+	}
+
+	// protected heartbeats can't be inspected
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordCCS1},
+		{ClientToServer, record(tlslayer.ContentTypeHeartbeat, []byte{0x01, 0x40, 0x00, 0x00})},
+	}, t)
+	if len(s.Heartbeats) != 2 || !s.Heartbeats[1].Encrypted || s.Heartbeats[1].Heartbleed {
+		t.Errorf("unexpected heartbeats: %v", s.Heartbeats)
+	}
+
+	// payload length is compared with the record, not with the bytes captured
+	s = NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, testRecordMultipleHsk1},
+		{ServerToClient, record(tlslayer.ContentTypeHeartbeat, []byte{0x03, 0x00, 0x00})},
+	}, t)
+	truncated := &tlslayer.TLSRecord{}
+	truncated.DecodeFromBytes([]byte{0x18, 0x03, 0x03, 0x00, 0x20, 0x01, 0x00, 0x10}, gopacket.NilDecodeFeedback)
+	if err := s.AddRecord(ClientToServer, truncated); err != nil {
+		t.Fatal("adding truncated record:", err)
+	}
+	if len(s.Heartbeats) != 2 || !s.Heartbeats[0].Malformed || s.Heartbeats[0].Encrypted {
+		t.Errorf("expected malformed heartbeat, got: %v", s.Heartbeats)
+	}
+	if !s.Heartbeats[1].Truncated || s.Heartbeats[1].Heartbleed || s.Heartbleed() {
+		t.Errorf("unexpected heartbleed in truncated record: %v", s.Heartbeats)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+
+package tlssession
+
+import (
+	"fmt"
+
+	"github.com/luisguillenc/tlslayer"
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+// AnomalyType is the type of a protocol anomaly found by the validator
+type AnomalyType uint8
+
+// AnomalyType possible values
+const (
+	AnomalyUnexpectedMessage AnomalyType = 1
+	AnomalyRenegotiation     AnomalyType = 2
+	AnomalyMissingMessage    AnomalyType = 3
+)
+
+func (a AnomalyType) getDesc() string {
+	switch a {
+	case AnomalyUnexpectedMessage:
+		return "unexpected_message"
+	case AnomalyRenegotiation:
+		return "renegotiation"
+	case AnomalyMissingMessage:
+		return "missing_message"
+	default:
+		return "unknown"
+	}
+}
+
+func (a AnomalyType) String() string {
+	return fmt.Sprintf("%s(%d)", a.getDesc(), a)
+}
+
+// Anomaly stores a message that breaks the handshake protocol. If type is
+// missing message, Handshake is the message that should have been sent before.
+type Anomaly struct {
+	Type      AnomalyType            `json:"type"`
+	Direction Direction              `json:"direction"`
+	Content   tlslayer.ContentType   `json:"content"`
+	Handshake tlsproto.HandshakeType `json:"handshake"`
+	// Index is the position of the message in the flow
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+func (a Anomaly) String() string {
+	msg := a.Content.String()
+	if a.Content == tlslayer.ContentTypeHandshake {
+		msg = a.Handshake.String()
+	}
+	return fmt.Sprintf("%s %s %s: %s", a.Type, a.Direction, msg, a.Reason)
+}
+
+// handshakeOrder stores the position of plaintext messages in the flight of each
+// direction in tls 1.0-1.2, messages not found aren't allowed in the direction
+var handshakeOrder = [2]map[tlsproto.HandshakeType]int{
+	ClientToServer: {
+		tlsproto.HandshakeTypeClientHello:       1,
+		tlsproto.HandshakeTypeCertificate:       2,
+		tlsproto.HandshakeTypeCertificateURL:    2,
+		tlsproto.HandshakeTypeClientKeyExchange: 3,
+		tlsproto.HandshakeTypeCertificateVerify: 4,
+	},
+	ServerToClient: {
+		tlsproto.HandshakeTypeHelloRequest:       0,
+		tlsproto.HandshakeTypeServerHello:        1,
+		tlsproto.HandshakeTypeCertificate:        2,
+		tlsproto.HandshakeTypeCertificateStatus:  3,
+		tlsproto.HandshakeTypeServerKeyExchange:  4,
+		tlsproto.HandshakeTypeCertificateRequest: 5,
+		tlsproto.HandshakeTypeServerHelloDone:    6,
+		tlsproto.HandshakeTypeNewSessionTicket:   7,
+	},
+}
+
+// dirState stores the state of the handshake in a direction
+type dirState struct {
+	last     int
+	seen     map[tlsproto.HandshakeType]bool
+	ccs      bool
+	finished bool
+}
+
+// Validator is a state machine that checks the order of the messages of
+// a tls 1.0-1.3 handshake and reports anomalies
+type Validator struct {
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+
+	offered13    bool
+	tls13        bool
+	helloRetry   bool
+	serverHello  bool
+	clientHellos int
+	index        int
+	state        [2]dirState
+}
+
+// NewValidator returns a validator for a new flow
+func NewValidator() *Validator {
+	v := &Validator{}
+	v.state[ClientToServer].seen = make(map[tlsproto.HandshakeType]bool)
+	v.state[ServerToClient].seen = make(map[tlsproto.HandshakeType]bool)
+	return v
+}
+
+func (v *Validator) addAnomaly(t AnomalyType, dir Direction, ctype tlslayer.ContentType, ht tlsproto.HandshakeType, reason string) {
+	v.Anomalies = append(v.Anomalies, Anomaly{
+		Type:      t,
+		Direction: dir,
+		Content:   ctype,
+		Handshake: ht,
+		Index:     v.index,
+		Reason:    reason,
+	})
+}
+
+func (v *Validator) unexpected(dir Direction, ht tlsproto.HandshakeType, reason string) {
+	v.addAnomaly(AnomalyUnexpectedMessage, dir, tlslayer.ContentTypeHandshake, ht, reason)
+}
+
+func (v *Validator) missing(dir Direction, ht tlsproto.HandshakeType, reason string) {
+	v.addAnomaly(AnomalyMissingMessage, dir, tlslayer.ContentTypeHandshake, ht, reason)
+}
+
+// Handshake checks a plaintext handshake message
+func (v *Validator) Handshake(dir Direction, hsk *tlsproto.Handshake) {
+	v.index++
+	st := &v.state[dir]
+	ht := hsk.Type
+	defer func() { st.seen[ht] = true }()
+
+	order, ok := handshakeOrder[dir][ht]
+	if !ok {
+		v.unexpected(dir, ht, "message not allowed in this direction")
+		return
+	}
+	// compatibility mode change_cipher_spec may be sent before server hello
+	compat := v.offered13 && !v.serverHello
+	if st.ccs && !v.tls13 && !compat {
+		v.unexpected(dir, ht, "plaintext message after change_cipher_spec")
+		return
+	}
+	switch ht {
+	case tlsproto.HandshakeTypeHelloRequest:
+		v.addAnomaly(AnomalyRenegotiation, dir, tlslayer.ContentTypeHandshake, ht, "server requested renegotiation")
+		return
+	case tlsproto.HandshakeTypeClientHello:
+		v.clientHellos++
+		if v.clientHellos > 1 {
+			if v.helloRetry && !v.serverHello && v.clientHellos == 2 {
+				// second client hello after hello retry request
+				return
+			}
+			v.addAnomaly(AnomalyRenegotiation, dir, tlslayer.ContentTypeHandshake, ht, "client hello sent again")
+			return
+		}
+		if ch := hsk.ClientHello; ch != nil {
+			v.offered13 = !ch.MaxOfferedVersion().Less(tlslayer.VersionTLS13)
+		}
+		st.last = order
+		return
+	case tlsproto.HandshakeTypeServerHello:
+		if !v.state[ClientToServer].seen[tlsproto.HandshakeTypeClientHello] {
+			v.unexpected(dir, ht, "server hello before client hello")
+		}
+		if sh := hsk.ServerHello; sh != nil && sh.IsHelloRetryRequest() {
+			if v.helloRetry {
+				v.unexpected(dir, ht, "hello retry request sent again")
+			}
+			v.helloRetry = true
+			return
+		}
+		if v.serverHello {
+			v.unexpected(dir, ht, "server hello sent again")
+			return
+		}
+		v.serverHello = true
+		if sh := hsk.ServerHello; sh != nil {
+			v.tls13 = sh.NegotiatedVersion().TLSVersion() == tlslayer.VersionTLS13
+		}
+		st.last = order
+		return
+	}
+	if v.tls13 {
+		v.unexpected(dir, ht, "plaintext message after tls 1.3 server hello")
+		return
+	}
+	if !v.serverHello {
+		v.unexpected(dir, ht, "message before server hello")
+		return
+	}
+	if order <= st.last {
+		v.unexpected(dir, ht, "message out of order")
+		return
+	}
+	if ht == tlsproto.HandshakeTypeClientKeyExchange && !v.state[ServerToClient].seen[tlsproto.HandshakeTypeServerHelloDone] {
+		v.missing(ServerToClient, tlsproto.HandshakeTypeServerHelloDone, "client key exchange before server hello done")
+	}
+	st.last = order
+}
+
+// ChangeCipherSpec checks a change_cipher_spec message
+func (v *Validator) ChangeCipherSpec(dir Direction) {
+	v.index++
+	st := &v.state[dir]
+	defer func() { st.ccs = true }()
+
+	if v.tls13 {
+		// compatibility mode allows one change_cipher_spec per direction
+		if st.ccs {
+			v.addAnomaly(AnomalyUnexpectedMessage, dir, tlslayer.ContentTypeChangeCipherSpec, 0, "change_cipher_spec sent again")
+		}
+		return
+	}
+	if !v.serverHello {
+		if dir == ClientToServer && v.offered13 && !st.ccs {
+			// compatibility mode before server hello
+			return
+		}
+		if dir == ServerToClient && v.offered13 && v.helloRetry && !st.ccs {
+			// compatibility mode after hello retry request
+			return
+		}
+		v.addAnomaly(AnomalyUnexpectedMessage, dir, tlslayer.ContentTypeChangeCipherSpec, 0, "change_cipher_spec before server hello")
+		return
+	}
+	if st.ccs {
+		v.addAnomaly(AnomalyUnexpectedMessage, dir, tlslayer.ContentTypeChangeCipherSpec, 0, "change_cipher_spec sent again")
+		return
+	}
+	server := &v.state[ServerToClient]
+	client := &v.state[ClientToServer]
+	// in a full handshake server sends its certificates and hello done
+	full := server.seen[tlsproto.HandshakeTypeServerHelloDone] || server.seen[tlsproto.HandshakeTypeCertificate] ||
+		server.seen[tlsproto.HandshakeTypeServerKeyExchange]
+	switch dir {
+	case ClientToServer:
+		if full && !client.seen[tlsproto.HandshakeTypeClientKeyExchange] {
+			v.missing(ClientToServer, tlsproto.HandshakeTypeClientKeyExchange, "change_cipher_spec without client key exchange")
+		}
+		if !full && !server.ccs {
+			v.missing(ServerToClient, tlsproto.HandshakeTypeFinished, "change_cipher_spec before server finished in abbreviated handshake")
+		}
+	case ServerToClient:
+		if full && !server.seen[tlsproto.HandshakeTypeServerHelloDone] {
+			v.missing(ServerToClient, tlsproto.HandshakeTypeServerHelloDone, "change_cipher_spec without server hello done")
+		}
+		if full && !client.finished {
+			v.missing(ClientToServer, tlsproto.HandshakeTypeFinished, "change_cipher_spec before client finished")
+		}
+	}
+}
+
+// Encrypted checks a protected record, it's the finished message if content is handshake
+func (v *Validator) Encrypted(dir Direction, ctype tlslayer.ContentType) {
+	v.index++
+	if v.tls13 || !v.serverHello {
+		return
+	}
+	st := &v.state[dir]
+	switch ctype {
+	case tlslayer.ContentTypeHandshake:
+		if !st.ccs {
+			v.addAnomaly(AnomalyUnexpectedMessage, dir, ctype, tlsproto.HandshakeTypeFinished, "protected handshake before change_cipher_spec")
+			return
+		}
+		if st.finished {
+			v.addAnomaly(AnomalyRenegotiation, dir, ctype, 0, "protected handshake after finished")
+			return
+		}
+		st.finished = true
+	case tlslayer.ContentTypeApplicationData:
+		if !st.finished {
+			v.missing(dir, tlsproto.HandshakeTypeFinished, "application data before finished")
+		}
+	}
+}
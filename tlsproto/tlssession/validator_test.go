@@ -0,0 +1,200 @@
+// Copyright 2018 Luis Guillén Civera <luisguillenc@gmail.com>. All rights reserved.
+package tlssession
+
+import (
+	"testing"
+
+	"github.com/luisguillenc/tlslayer"
+	"github.com/luisguillenc/tlslayer/tlsproto"
+)
+
+// testRecordClientKeyExchange is a plaintext client key exchange record
+var testRecordClientKeyExchange = record(tlslayer.ContentTypeHandshake, []byte{0x10, 0x00, 0x00, 0x02, 0x00, 0x00})
+
+func TestValidatorAnomalies(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []testRecord
+		want    AnomalyType
+		content tlslayer.ContentType
+		hsk     tlsproto.HandshakeType
+	}{
+		{
+			"server hello before client hello",
+			[]testRecord{
+				{ServerToClient, testRecordMultipleHsk1},
+			},
+			AnomalyUnexpectedMessage, tlslayer.ContentTypeHandshake, tlsproto.HandshakeTypeServerHello,
+		},
+		{
+			"ccs before server hello",
+			[]testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, testRecordCCS1},
+			},
+			AnomalyUnexpectedMessage, tlslayer.ContentTypeChangeCipherSpec, 0,
+		},
+		{
+			"certificate after finished",
+			[]testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, testRecordMultipleHsk1},
+				{ClientToServer, testRecordClientKeyExchange},
+				{ClientToServer, testRecordCCS1},
+				{ClientToServer, testRecordEncrypted1},
+				{ServerToClient, testRecordCCS1},
+				{ServerToClient, testRecordEncrypted1},
+				{ServerToClient, testRecordCertificate1},
+			},
+			AnomalyUnexpectedMessage, tlslayer.ContentTypeHandshake, tlsproto.HandshakeTypeCertificate,
+		},
+		{
+			"client hello sent again",
+			[]testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, testRecordMultipleHsk1},
+				{ClientToServer, testRecordClientHello1},
+			},
+			AnomalyRenegotiation, tlslayer.ContentTypeHandshake, tlsproto.HandshakeTypeClientHello,
+		},
+		{
+			"protected handshake after finished",
+			[]testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, testRecordMultipleHsk1},
+				{ClientToServer, testRecordClientKeyExchange},
+				{ClientToServer, testRecordCCS1},
+				{ClientToServer, testRecordEncrypted1},
+				{ClientToServer, testRecordEncrypted1},
+			},
+			AnomalyRenegotiation, tlslayer.ContentTypeHandshake, 0,
+		},
+		{
+			"missing client key exchange",
+			[]testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, testRecordMultipleHsk1},
+				{ClientToServer, testRecordCCS1},
+			},
+			AnomalyMissingMessage, tlslayer.ContentTypeHandshake, tlsproto.HandshakeTypeClientKeyExchange,
+		},
+		{
+			"application data before finished",
+			[]testRecord{
+				{ClientToServer, testRecordClientHello1},
+				{ServerToClient, testRecordMultipleHsk1},
+				{ClientToServer, testRecordClientKeyExchange},
+				{ClientToServer, testRecordAppData1},
+			},
+			AnomalyMissingMessage, tlslayer.ContentTypeHandshake, tlsproto.HandshakeTypeFinished,
+		},
+	}
+	for _, test := range tests {
+		s := NewSession()
+		addRecords(s, test.records, t)
+		if len(s.Anomalies) != 1 {
+			t.Errorf("%s: expected one anomaly, got: %v", test.name, s.Anomalies)
+			continue
+		}
+		a := s.Anomalies[0]
+		if a.Type != test.want || a.Content != test.content || a.Handshake != test.hsk {
+			t.Errorf("%s: unexpected anomaly: %v", test.name, a)
+		}
+	}
+}
+
+func TestValidatorFullHandshake(t *testing.T) {
+	s := NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, testRecordMultipleHsk1},
+		{ClientToServer, testRecordClientKeyExchange},
+		{ClientToServer, testRecordCCS1},
+		{ClientToServer, testRecordEncrypted1},
+		{ServerToClient, testRecordCCS1},
+		{ServerToClient, testRecordEncrypted1},
+		{ClientToServer, testRecordAppData1},
+		{ServerToClient, testRecordAppData1},
+	}, t)
+	if len(s.Anomalies) != 0 {
+		t.Errorf("unexpected anomalies: %v", s.Anomalies)
+	}
+	if !s.Complete {
+		t.Error("expected session completed")
+	}
+}
+
+func TestValidatorTLS13(t *testing.T) {
+	s := NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ClientToServer, testRecordCCS1},
+		{ServerToClient, serverHello13(false)},
+		{ServerToClient, testRecordCCS1},
+		{ServerToClient, testRecordAppData1},
+		{ClientToServer, testRecordAppData1},
+	}, t)
+	if len(s.Anomalies) != 0 {
+		t.Errorf("unexpected anomalies: %v", s.Anomalies)
+	}
+
+	// hello retry request has a fixed random
+	hrr := serverHello13(false)
+	copy(hrr[11:43], []byte{
+		0xcf, 0x21, 0xad, 0x74, 0xe5, 0x9a, 0x61, 0x11, 0xbe, 0x1d, 0x8c, 0x02, 0x1e, 0x65, 0xb8, 0x91,
+		0xc2, 0xa2, 0x11, 0x16, 0x7a, 0xbb, 0x8c, 0x5e, 0x07, 0x9e, 0x09, 0xe2, 0xc8, 0xa8, 0x33, 0x9c,
+	})
+	// compatibility mode (rfc8446 appendix D.4)
+	s = NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, hrr},
+		{ServerToClient, testRecordCCS1},
+		{ClientToServer, testRecordCCS1},
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, serverHello13(false)},
+		{ServerToClient, testRecordAppData1},
+		{ClientToServer, testRecordAppData1},
+	}, t)
+	if len(s.Anomalies) != 0 {
+		t.Errorf("unexpected anomalies: %v", s.Anomalies)
+	}
+
+	// server change_cipher_spec is sent once
+	s = NewSession()
+	addRecords(s, []testRecord{
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, hrr},
+		{ServerToClient, testRecordCCS1},
+		{ClientToServer, testRecordClientHello1},
+		{ServerToClient, serverHello13(false)},
+		{ServerToClient, testRecordCCS1},
+	}, t)
+	if len(s.Anomalies) != 1 {
+		t.Errorf("expected anomalies: 1, got: %v", s.Anomalies)
+	}
+}
+
+func TestValidatorDTLSOffered(t *testing.T) {
+	tests := []struct {
+		versions []tlsproto.SupportedVersion
+		want     bool
+	}{
+		{nil, false},
+		{[]tlsproto.SupportedVersion{0xfefd}, false},
+		{[]tlsproto.SupportedVersion{0xfefc, 0xfefd}, true},
+	}
+	for _, test := range tests {
+		v := NewValidator()
+		v.Handshake(ClientToServer, &tlsproto.Handshake{
+			Type: tlsproto.HandshakeTypeClientHello,
+			ClientHello: &tlsproto.ClientHelloData{
+				ClientVersion: tlslayer.VersionDTLS12,
+				ExtInfo:       &tlsproto.ExtensionsInfo{SupportedVersions: test.versions},
+			},
+		})
+		if v.offered13 != test.want {
+			t.Errorf("%v: expected offered tls 1.3: %v", test.versions, test.want)
+		}
+	}
+}
@@ -14,6 +14,12 @@ const (
 	MaxTLSRecordSize uint16 = 16384 + 1024
 )
 
+// Maximum expansion of a protected record over the plaintext length
+const (
+	MaxTLS12RecordExpansion uint16 = 2048
+	MaxTLS13RecordExpansion uint16 = 256
+)
+
 // TLSRecord is the struct for SSL message records
 type TLSRecord struct {
 	layers.BaseLayer
@@ -21,6 +27,12 @@ type TLSRecord struct {
 	Type    ContentType     `json:"type"`
 	Version ProtocolVersion `json:"version"`
 	Len     uint16          `json:"len"`
+
+	// Limit is the maximum record length negotiated on the connection,
+	// if it's zero only MaxTLSRecordSize is checked
+	Limit uint16 `json:"-"`
+	// Oversized is true if record length exceeds the negotiated Limit
+	Oversized bool `json:"oversized,omitempty"`
 }
 
 func (tls *TLSRecord) String() string {
@@ -36,6 +48,16 @@ var LayerTypeTLSRecord = gopacket.RegisterLayerType(
 	},
 )
 
+// contentTypeLayers stores the layer types that decode the payload of records
+var contentTypeLayers [256]gopacket.LayerType
+
+// RegisterContentTypeLayerType sets the layer type returned by NextLayerType for
+// records of the content type. It's used by packages that decode tls messages
+// and it must not be called while packets are decoded.
+func RegisterContentTypeLayerType(ctype ContentType, ltype gopacket.LayerType) {
+	contentTypeLayers[ctype] = ltype
+}
+
 // CanDecode satisfaces the interface
 func (tls *TLSRecord) CanDecode() gopacket.LayerClass {
 	return LayerTypeTLSRecord
@@ -46,8 +68,12 @@ func (tls *TLSRecord) LayerType() gopacket.LayerType {
 	return LayerTypeTLSRecord
 }
 
-// NextLayerType satisfaces the interface
+// NextLayerType satisfaces the interface, it returns the layer type registered
+// for the content type of the record or payload if there isn't any
 func (tls *TLSRecord) NextLayerType() gopacket.LayerType {
+	if ltype := contentTypeLayers[tls.Type]; ltype != gopacket.LayerTypeZero {
+		return ltype
+	}
 	return gopacket.LayerTypePayload
 }
 
@@ -66,6 +92,10 @@ func decodeTLSRecord(data []byte, p gopacket.PacketBuilder) error {
 
 	p.AddLayer(tls)
 	p.SetApplicationLayer(tls)
+	// payload remains in the record if there isn't a layer for the content type
+	if next := tls.NextLayerType(); next != gopacket.LayerTypePayload {
+		return p.NextDecoder(next)
+	}
 
 	return nil
 }
@@ -106,16 +136,21 @@ func (tls *TLSRecord) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) e
 	tls.Type = ctype
 	tls.Version = pversion
 	tls.Len = msglen
+	tls.Oversized = tls.Limit > 0 && msglen > tls.Limit
 	tls.BaseLayer.Contents = data[:5]
 	//check if data has payload
 	if len(data) <= 5 {
 		return ErrTLSPayloadEmpty
 	}
-	tls.BaseLayer.Payload = data[5 : 5+msglen]
 	// checks if completed payload
-	if len(tls.BaseLayer.Payload) != int(msglen) {
+	if len(data)-5 < int(msglen) {
+		tls.BaseLayer.Payload = data[5:]
+		if df != nil {
+			df.SetTruncated()
+		}
 		return ErrTLSWrongPayload
 	}
+	tls.BaseLayer.Payload = data[5 : 5+msglen]
 	return nil
 }
 
@@ -139,6 +174,11 @@ func (tls *TLSRecord) IsApplicationData() bool {
 	return tls.Type == ContentTypeApplicationData
 }
 
+// IsHeartbeat returns true if record uses Heartbeat protocol
+func (tls *TLSRecord) IsHeartbeat() bool {
+	return tls.Type == ContentTypeHeartbeat
+}
+
 // IsClear returns true if payload of the record was cleared
 func (tls *TLSRecord) IsClear() bool {
 	return tls.Len > 0 && (len(tls.BaseLayer.Payload) == 0)
@@ -154,6 +194,8 @@ func Copy(dst, src *TLSRecord) {
 	dst.Type = src.Type
 	dst.Version = src.Version
 	dst.Len = src.Len
+	dst.Limit = src.Limit
+	dst.Oversized = src.Oversized
 	dst.BaseLayer.Contents = make([]byte, len(src.BaseLayer.Contents), len(src.BaseLayer.Contents))
 	copy(dst.BaseLayer.Contents, src.BaseLayer.Contents)
 	dst.BaseLayer.Payload = make([]byte, len(src.BaseLayer.Payload), len(src.BaseLayer.Payload))
@@ -165,6 +207,8 @@ func CopyHeader(dst, src *TLSRecord) {
 	dst.Type = src.Type
 	dst.Version = src.Version
 	dst.Len = src.Len
+	dst.Limit = src.Limit
+	dst.Oversized = src.Oversized
 	dst.BaseLayer.Contents = make([]byte, len(src.BaseLayer.Contents), len(src.BaseLayer.Contents))
 	copy(dst.BaseLayer.Contents, src.BaseLayer.Contents)
 	dst.BaseLayer.Payload = nil
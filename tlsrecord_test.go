@@ -122,6 +122,14 @@ func TestHasHeader(t *testing.T) {
 	if HasHeader(header) {
 		t.Error("Error checking tls header")
 	}
+
+	// dtls only content types
+	for _, ctype := range []ContentType{ContentTypeTLS12CID, ContentTypeACK} {
+		header = []byte{byte(ctype), 0x03, 0x03, 0x00, 0x02}
+		if HasHeader(header) {
+			t.Errorf("unexpected tls header with type: %v", ctype)
+		}
+	}
 }
 
 func TestDecodeRecord(t *testing.T) {
@@ -210,6 +218,49 @@ func TestDecodeRecord(t *testing.T) {
 	}
 }
 
+func TestDecodeRecordLimit(t *testing.T) {
+	tlsrecord := &TLSRecord{Limit: 512}
+	if err := tlsrecord.DecodeFromBytes(testRecordServerHello, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("No TLSRecord layer type found in byte slice")
+	}
+	if tlsrecord.Oversized {
+		t.Error("Record flagged as oversized under limit")
+	}
+
+	tlsrecord.Limit = 64
+	if err := tlsrecord.DecodeFromBytes(testRecordServerHello, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("No TLSRecord layer type found in byte slice")
+	}
+	if !tlsrecord.Oversized {
+		t.Error("Record not flagged as oversized over limit")
+	}
+}
+
+func TestDecodeRecordTruncated(t *testing.T) {
+	tlsrecord := &TLSRecord{}
+	err := tlsrecord.DecodeFromBytes(testRecordServerHello[:50], gopacket.NilDecodeFeedback)
+	if err != ErrTLSWrongPayload {
+		t.Errorf("expected error: %v, got: %v", ErrTLSWrongPayload, err)
+	}
+	if len(tlsrecord.Payload()) != 45 {
+		t.Errorf("expected truncated payload: 45, got: %v", len(tlsrecord.Payload()))
+	}
+}
+
+func TestDecodeRecordHeartbeat(t *testing.T) {
+	data := []byte{0x18, 0x03, 0x02, 0x00, 0x03, 0x01, 0x40, 0x00}
+	tlsrecord := &TLSRecord{}
+	if err := tlsrecord.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal("decoding heartbeat record:", err)
+	}
+	if !tlsrecord.IsHeartbeat() || tlsrecord.Type.String() != "heartbeat(24)" {
+		t.Errorf("expected heartbeat record, got: %v", tlsrecord.Type)
+	}
+	if tlsrecord.Len != 3 {
+		t.Errorf("expected len: 3, got: %v", tlsrecord.Len)
+	}
+}
+
 func TestDecodePacket(t *testing.T) {
 	p := gopacket.NewPacket(testPacketClient, layers.LinkTypeEthernet, testDecodeOptions)
 	if p.ErrorLayer() != nil {
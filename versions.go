@@ -16,6 +16,13 @@ const (
 	VersionTLS13 ProtocolVersion = 0x304
 )
 
+// Version dtls version possible values, dtls 1.1 doesn't exist
+const (
+	VersionDTLS10 ProtocolVersion = 0xfeff
+	VersionDTLS12 ProtocolVersion = 0xfefd
+	VersionDTLS13 ProtocolVersion = 0xfefc
+)
+
 func (v ProtocolVersion) getDesc() string {
 	switch v {
 	case VersionSSL30:
@@ -28,6 +35,12 @@ func (v ProtocolVersion) getDesc() string {
 		return "TLS_1.2"
 	case VersionTLS13:
 		return "TLS_1.3"
+	case VersionDTLS10:
+		return "DTLS_1.0"
+	case VersionDTLS12:
+		return "DTLS_1.2"
+	case VersionDTLS13:
+		return "DTLS_1.3"
 	default:
 		return "unknown"
 	}
@@ -38,7 +51,33 @@ func (v ProtocolVersion) String() string {
 	return fmt.Sprintf("%s(%d)", v.getDesc(), v)
 }
 
-// IsValid method checks if it's a valid value
+// TLSVersion returns the tls version a dtls version is based on, dtls 1.0 is
+// tls 1.1. Other values are returned unchanged.
+func (v ProtocolVersion) TLSVersion() ProtocolVersion {
+	switch v {
+	case VersionDTLS10:
+		return VersionTLS11
+	case VersionDTLS12:
+		return VersionTLS12
+	case VersionDTLS13:
+		return VersionTLS13
+	default:
+		return v
+	}
+}
+
+// Less returns true if v is older than other. Values of dtls versions decrease
+// with newer versions, so they are compared using their tls versions.
+func (v ProtocolVersion) Less(other ProtocolVersion) bool {
+	return v.TLSVersion() < other.TLSVersion()
+}
+
+// IsDTLS returns true if it's a dtls version
+func (v ProtocolVersion) IsDTLS() bool {
+	return v == VersionDTLS10 || v == VersionDTLS12 || v == VersionDTLS13
+}
+
+// IsValid method checks if it's a valid tls value, dtls versions aren't valid in tls records
 func (v ProtocolVersion) IsValid() bool {
 	if v >= 768 && v <= 772 {
 		return true